@@ -6,25 +6,66 @@ import (
 	"bridge-serial/pkg/logger"
 	"flag"
 	"log"
+	"os"
 )
 
 func main() {
-	mode := flag.String("mode", "production", "mode of the application")
+	settings := config.RegisterFlags(flag.CommandLine)
+	configFile := flag.String("config", "", "config file, config.json in the config directory by default")
+	printConfig := flag.Bool("print-config", false, "print the effective config and the source of each value, then exit")
+	upgradeConfig := flag.Bool("upgrade-config", false, "rewrite the config file in the current schema, keeping a backup of the original, then exit")
+	flag.Parse()
 
-	cfg, err := config.LoadConfig(*mode)
+	loadOptions := config.LoadOptions{
+		File:  *configFile,
+		Env:   os.Environ(),
+		Flags: settings.Values(),
+	}
+	loaded, err := config.Load(loadOptions)
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
+	if *upgradeConfig {
+		if loaded.File == "" {
+			log.Fatalf("No config file to upgrade at %s", loaded.Config.GetDefaultConfigPath())
+		}
+		version, err := config.UpgradeFile(loaded.File)
+		if err != nil {
+			log.Fatalf("Failed to upgrade config: %v", err)
+		}
+		if version == config.SchemaVersion {
+			log.Printf("%s already uses schema version %d", loaded.File, version)
+		} else {
+			log.Printf("Upgraded %s from schema version %d to %d", loaded.File, version, config.SchemaVersion)
+		}
+		return
+	}
+	if *printConfig {
+		loaded.PrintConfig(os.Stdout)
+	}
+	cfg := loaded.Config
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Invalid config:\n%v", err)
+	}
+	if *printConfig {
+		return
+	}
 
 	err = logger.Init(logger.INFO, "./logs")
 	if err != nil {
 		log.Fatal("Failed to initialize logger:", err)
 	}
+	for _, note := range loaded.Notes {
+		logger.Info("%s", note)
+	}
 
 	app, err := runner.NewApp(cfg)
 	if err != nil {
 		log.Fatalf("Failed to create app: %v", err)
 	}
+	if err := app.WatchConfig(loadOptions); err != nil {
+		logger.Warn("config reload disabled: %v", err)
+	}
 	app.Run()
 
 }
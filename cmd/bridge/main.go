@@ -3,26 +3,88 @@ package main
 import (
 	"bridge-serial/config"
 	"bridge-serial/internal/bridge"
+	"bridge-serial/internal/serial"
 	"bridge-serial/pkg/logger"
 	"flag"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
 )
 
 func main() {
-	mode := flag.String("mode", "production", "mode of the application")
-	cfg, err := config.LoadConfig(*mode)
+	settings := config.RegisterFlags(flag.CommandLine)
+	configFile := flag.String("config", "", "config file, config.json in the config directory by default")
+	printConfig := flag.Bool("print-config", false, "print the effective config and the source of each value, then exit")
+	upgradeConfig := flag.Bool("upgrade-config", false, "rewrite the config file in the current schema, keeping a backup of the original, then exit")
+	flag.Parse()
+
+	loadOptions := config.LoadOptions{
+		File:  *configFile,
+		Env:   os.Environ(),
+		Flags: settings.Values(),
+	}
+	loaded, err := config.Load(loadOptions)
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
+	if *upgradeConfig {
+		if loaded.File == "" {
+			log.Fatalf("No config file to upgrade at %s", loaded.Config.GetDefaultConfigPath())
+		}
+		version, err := config.UpgradeFile(loaded.File)
+		if err != nil {
+			log.Fatalf("Failed to upgrade config: %v", err)
+		}
+		if version == config.SchemaVersion {
+			log.Printf("%s already uses schema version %d", loaded.File, version)
+		} else {
+			log.Printf("Upgraded %s from schema version %d to %d", loaded.File, version, config.SchemaVersion)
+		}
+		return
+	}
+	if *printConfig {
+		loaded.PrintConfig(os.Stdout)
+	}
+	cfg := loaded.Config
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Invalid config:\n%v", err)
+	}
+	if *printConfig {
+		return
+	}
 
 	err = logger.Init(logger.INFO, "./logs")
 	if err != nil {
 		log.Fatal("Failed to initialize logger:", err)
 	}
+	for _, note := range loaded.Notes {
+		logger.Info("%s", note)
+	}
 
-	bManager := bridge.NewBridgeManager(cfg)
+	opener, err := serial.NewPortOpener(&cfg.SerialBridge)
+	if err != nil {
+		log.Fatalf("Failed to create port opener: %v", err)
+	}
+
+	bManager := bridge.NewBridgeManager(cfg, opener)
 	if err := bManager.Start(); err != nil {
 		log.Fatalf("Failed to start bridge manager: %v", err)
 	}
 
+	watcher, err := bManager.WatchConfig(loadOptions)
+	if err != nil {
+		logger.Warn("config reload disabled: %v", err)
+	} else {
+		defer watcher.Close()
+	}
+
+	// run until interrupted, then close the port and flush the forwarder
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	<-sig
+
+	if err := bManager.Stop(); err != nil {
+		log.Fatalf("Failed to stop bridge manager: %v", err)
+	}
 }
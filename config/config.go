@@ -12,40 +12,179 @@ import (
 )
 
 type Config struct {
-	App          AppConfig
-	SerialBridge SerialBridgeConfig
-	HTTPClient   HTTPClientConfig
-	SocketConfig SocketConfig
+	// Version is the config.json schema version, see SchemaVersion
+	Version int `json:"version"`
 
-	User     string
-	Password string
+	App          AppConfig          `json:"app"`
+	SerialBridge SerialBridgeConfig `json:"serial_bridge"`
+	HTTPClient   HTTPClientConfig   `json:"http_client"`
+	MQTT         MQTTConfig         `json:"mqtt"`
+	SocketConfig SocketConfig       `json:"socket"`
+	Auth         AuthConfig         `json:"auth"`
+	TLS          TLSConfig          `json:"tls"`
+	Health       HealthConfig       `json:"health"`
+
+	User     string `json:"user"`
+	Password string `json:"password"`
 }
 
 type AppConfig struct {
-	AppName     string
-	WindowTitle string
-	Mode        string
+	AppName     string `json:"app_name"`
+	WindowTitle string `json:"window_title"`
+	Mode        string `json:"mode"`
 }
 
 type SerialBridgeConfig struct {
-	DataBits int
-	Parity   serial.Parity
-	StopBits serial.StopBits
-	Timeout  time.Duration
-	BaudRate int
+	DataBits int             `json:"data_bits"`
+	Parity   serial.Parity   `json:"parity"`
+	StopBits serial.StopBits `json:"stop_bits"`
+	Timeout  time.Duration   `json:"timeout"`
+	BaudRate int             `json:"baud_rate"`
+
+	// Transport selects how the port is reached: "serial" (default) for a
+	// local port or "tcp" for a device server listening on TCPAddress.
+	Transport  string `json:"transport"`
+	TCPAddress string `json:"tcp_address"`
+
+	// Protocol names the parser used to decode scale frames, see protocol.Names
+	Protocol string `json:"protocol"`
+
+	// Mode is "stream" when the scale sends weights on its own, or "poll" to
+	// send PollCommand every PollInterval and wait ResponseTimeout for the
+	// reply. An empty PollCommand uses the protocol's weigh command.
+	Mode            string        `json:"mode"`
+	PollCommand     string        `json:"poll_command"`
+	PollInterval    time.Duration `json:"poll_interval"`
+	ResponseTimeout time.Duration `json:"response_timeout"`
+
+	// ReconnectInterval is the initial delay between reconnect attempts after
+	// the port is lost; it doubles on each failure up to MaxReconnectInterval.
+	ReconnectInterval    time.Duration `json:"reconnect_interval"`
+	MaxReconnectInterval time.Duration `json:"max_reconnect_interval"`
+
+	// Devices lists the rules used to pick the serial port, tried in
+	// ascending Priority order. The first enumerated port matching a rule wins.
+	Devices []DeviceMatchRule `json:"devices"`
 }
 
+// DeviceMatchRule describes a serial port to connect to. Every non-empty
+// field has to match for the rule to select a port.
+type DeviceMatchRule struct {
+	Priority     int    `json:"priority"`
+	PortName     string `json:"port_name"` // exact port name, e.g. COM3 or /dev/ttyS0
+	VID          string `json:"vid"`       // USB vendor ID in hex, e.g. 067B
+	PID          string `json:"pid"`       // USB product ID in hex, e.g. 2303
+	SerialNumber string `json:"serial_number"`
+	Product      string `json:"product"` // glob matched against the product string, e.g. "*FT232*"
+}
+
+// HTTPClientConfig controls forwarding of readings to a backend. When
+// Enabled, each reading is POSTed to BaseURL + Path with AuthHeader set to
+// AuthToken, retried with exponential backoff between RetryBackoff and
+// MaxBackoff. At most QueueSize readings wait for delivery.
+//
+// With Durable set, readings are persisted in QueueDir (relative paths are
+// resolved against the config directory) before delivery and retried until
+// the endpoint accepts them, surviving restarts; the oldest readings are
+// dropped once the queue exceeds QueueSize, MaxQueueBytes or MaxQueueAge.
+// Otherwise they are kept in memory, carried over when a reload changes these
+// settings, and given up after MaxRetries retries.
 type HTTPClientConfig struct {
-	BaseURL string
+	BaseURL string `json:"base_url"`
+
+	Enabled      bool          `json:"enabled"`
+	Path         string        `json:"path"`
+	AuthHeader   string        `json:"auth_header"`
+	AuthToken    string        `json:"auth_token"`
+	Timeout      time.Duration `json:"timeout"`
+	MaxRetries   int           `json:"max_retries"`
+	RetryBackoff time.Duration `json:"retry_backoff"`
+	MaxBackoff   time.Duration `json:"max_backoff"`
+	QueueSize    int           `json:"queue_size"`
+
+	Durable       bool          `json:"durable"`
+	QueueDir      string        `json:"queue_dir"`
+	MaxQueueBytes int64         `json:"max_queue_bytes"`
+	MaxQueueAge   time.Duration `json:"max_queue_age"`
+}
+
+// MQTTConfig publishes readings to an MQTT 3.1.1 broker. Each reading is
+// published as JSON to TopicTemplate with {port} and {client_id} replaced,
+// retained when Retain is set. StatusTopic holds "online" while the bridge is
+// connected and the broker publishes "offline" there when the bridge is lost.
+// The payloads "tare", "zero" and "print" on CommandTopic are sent to the scale.
+type MQTTConfig struct {
+	Enabled  bool   `json:"enabled"`
+	Broker   string `json:"broker"` // host:port or tcp://host:port
+	ClientID string `json:"client_id"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+
+	TopicTemplate string        `json:"topic_template"`
+	StatusTopic   string        `json:"status_topic"`
+	CommandTopic  string        `json:"command_topic"`
+	Retain        bool          `json:"retain"`
+	KeepAlive     time.Duration `json:"keep_alive"`
 }
 
 type SocketConfig struct {
-	Port          string
-	RetryInterval time.Duration
+	Port          string        `json:"port"`
+	RetryInterval time.Duration `json:"retry_interval"`
+
+	// AllowedOrigins lists the browser origins allowed to connect, see cors.Policy
+	AllowedOrigins []string `json:"allowed_origins"`
+
+	// EventHistory is how many recent messages GET /events keeps for clients
+	// resuming with Last-Event-ID
+	EventHistory int `json:"event_history"`
+}
+
+// AuthConfig controls token authentication of the WebSocket and REST endpoints.
+// Clients authenticate with the API key, or with User/Password over Basic auth.
+type AuthConfig struct {
+	Enabled bool `json:"enabled"`
+	// APIKeyFile is where the generated API key is stored, relative paths are
+	// resolved against the config directory
+	APIKeyFile string `json:"api_key_file"`
 }
 
+// TLSConfig enables wss:// and https:// on the bridge HTTP server. Without
+// CertFile and KeyFile a local CA and localhost certificate are generated in
+// CertDir; CAFile optionally points /cert at the CA of operator supplied files.
+type TLSConfig struct {
+	Enabled  bool   `json:"enabled"`
+	CertDir  string `json:"cert_dir"`
+	CertFile string `json:"cert_file"`
+	KeyFile  string `json:"key_file"`
+	CAFile   string `json:"ca_file"`
+}
+
+// HealthConfig sets when GET /health/ready reports the bridge as not ready.
+// A zero value disables the check.
+type HealthConfig struct {
+	// MaxReadingAge is how long the scale may stay silent, counted from the
+	// last reading or, before the first one, from when the port was opened
+	MaxReadingAge time.Duration `json:"max_reading_age"`
+	// MaxParseErrorRate is the tolerated share of recent frames that failed to parse
+	MaxParseErrorRate float64 `json:"max_parse_error_rate"`
+	// MaxForwarderBacklog is how many readings may wait for the HTTP forwarder
+	MaxForwarderBacklog int `json:"max_forwarder_backlog"`
+}
+
+// LoadConfig returns the defaults merged with config.json and BRIDGE_SERIAL_*
+// environment variables; commands also apply flags through Load
 func LoadConfig(mode string) (*Config, error) {
+	loaded, err := Load(LoadOptions{Mode: mode, Env: os.Environ()})
+	if err != nil {
+		return nil, err
+	}
+	return loaded.Config, nil
+}
+
+// defaultConfig returns the built-in defaults
+func defaultConfig(mode string) *Config {
 	return &Config{
+		Version: SchemaVersion,
 		App: AppConfig{
 			AppName:     "rapier-bridge",
 			WindowTitle: "Rapier Bridge Serial",
@@ -57,21 +196,93 @@ func LoadConfig(mode string) (*Config, error) {
 			StopBits: serial.OneStopBit,
 			Timeout:  10 * time.Second,
 			BaudRate: 9600,
+
+			Transport: "serial",
+			Protocol:  "whitespace-fields",
+
+			Mode:            "stream",
+			PollInterval:    500 * time.Millisecond,
+			ResponseTimeout: 2 * time.Second,
+
+			ReconnectInterval:    1 * time.Second,
+			MaxReconnectInterval: 30 * time.Second,
+
+			Devices: []DeviceMatchRule{
+				{Priority: 0, VID: "067B", PID: "2303"}, // Prolific PL2303
+				{Priority: 1, VID: "0403", PID: "6001"}, // FTDI FT232R
+				{Priority: 1, VID: "1A86", PID: "7523"}, // QinHeng CH340
+			},
 		},
 		HTTPClient: HTTPClientConfig{
 			BaseURL: "http://localhost:8080",
+
+			Enabled:      false,
+			Path:         "/api/scale-data",
+			AuthHeader:   "Authorization",
+			Timeout:      5 * time.Second,
+			MaxRetries:   5,
+			RetryBackoff: 1 * time.Second,
+			MaxBackoff:   30 * time.Second,
+			QueueSize:    1000,
+
+			Durable:       true,
+			QueueDir:      "queue",
+			MaxQueueBytes: 64 << 20,
+			MaxQueueAge:   7 * 24 * time.Hour,
+		},
+		MQTT: MQTTConfig{
+			Enabled:  false,
+			Broker:   "tcp://localhost:1883",
+			ClientID: "bridge-serial",
+
+			TopicTemplate: "scales/{port}/weight",
+			StatusTopic:   "scales/{client_id}/status",
+			CommandTopic:  "scales/{client_id}/command",
+			Retain:        true,
+			KeepAlive:     30 * time.Second,
 		},
 		SocketConfig: SocketConfig{
 			Port:          ":8001",
 			RetryInterval: 5 * time.Second,
+			AllowedOrigins: []string{
+				"localhost",
+				"127.0.0.1",
+			},
+			EventHistory: 256,
+		},
+		Auth: AuthConfig{
+			Enabled:    true,
+			APIKeyFile: "api_key",
+		},
+		TLS: TLSConfig{
+			Enabled: false,
+			CertDir: "tls",
 		},
-	}, nil
+		Health: HealthConfig{
+			MaxReadingAge:       60 * time.Second,
+			MaxParseErrorRate:   0.5,
+			MaxForwarderBacklog: 0,
+		},
+	}
 }
 
 func (c *Config) GetDefaultConfigPath() string {
 	return filepath.Join(getConfigDir(c.App.AppName), "config.json")
 }
 
+// GetConfigDir returns the directory holding config.json and generated files
+func (c *Config) GetConfigDir() string {
+	return getConfigDir(c.App.AppName)
+}
+
+// ResolvePath resolves a path relative to the config directory
+func (c *Config) ResolvePath(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(c.GetConfigDir(), path)
+}
+
 func getConfigDir(appName string) string {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
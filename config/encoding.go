@@ -0,0 +1,282 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+// The config structs encode durations as strings such as "10s", parity as
+// its name and stop bits as "1", "1.5" or "2", and reject unknown keys so
+// typos in config.json are reported instead of ignored.
+
+func (c Config) MarshalJSON() ([]byte, error) { return marshalFields(reflect.ValueOf(c)) }
+func (c *Config) UnmarshalJSON(data []byte) error {
+	return unmarshalFields(data, reflect.ValueOf(c).Elem())
+}
+
+func (c AppConfig) MarshalJSON() ([]byte, error) { return marshalFields(reflect.ValueOf(c)) }
+func (c *AppConfig) UnmarshalJSON(data []byte) error {
+	return unmarshalFields(data, reflect.ValueOf(c).Elem())
+}
+
+func (c SerialBridgeConfig) MarshalJSON() ([]byte, error) { return marshalFields(reflect.ValueOf(c)) }
+func (c *SerialBridgeConfig) UnmarshalJSON(data []byte) error {
+	return unmarshalFields(data, reflect.ValueOf(c).Elem())
+}
+
+func (r DeviceMatchRule) MarshalJSON() ([]byte, error) { return marshalFields(reflect.ValueOf(r)) }
+func (r *DeviceMatchRule) UnmarshalJSON(data []byte) error {
+	// decoding a slice reuses its elements, a rule must not inherit old fields
+	*r = DeviceMatchRule{}
+	return unmarshalFields(data, reflect.ValueOf(r).Elem())
+}
+
+func (c HTTPClientConfig) MarshalJSON() ([]byte, error) { return marshalFields(reflect.ValueOf(c)) }
+func (c *HTTPClientConfig) UnmarshalJSON(data []byte) error {
+	return unmarshalFields(data, reflect.ValueOf(c).Elem())
+}
+
+func (c MQTTConfig) MarshalJSON() ([]byte, error) { return marshalFields(reflect.ValueOf(c)) }
+func (c *MQTTConfig) UnmarshalJSON(data []byte) error {
+	return unmarshalFields(data, reflect.ValueOf(c).Elem())
+}
+
+func (c SocketConfig) MarshalJSON() ([]byte, error) { return marshalFields(reflect.ValueOf(c)) }
+func (c *SocketConfig) UnmarshalJSON(data []byte) error {
+	return unmarshalFields(data, reflect.ValueOf(c).Elem())
+}
+
+func (c AuthConfig) MarshalJSON() ([]byte, error) { return marshalFields(reflect.ValueOf(c)) }
+func (c *AuthConfig) UnmarshalJSON(data []byte) error {
+	return unmarshalFields(data, reflect.ValueOf(c).Elem())
+}
+
+func (c TLSConfig) MarshalJSON() ([]byte, error) { return marshalFields(reflect.ValueOf(c)) }
+func (c *TLSConfig) UnmarshalJSON(data []byte) error {
+	return unmarshalFields(data, reflect.ValueOf(c).Elem())
+}
+
+func (c HealthConfig) MarshalJSON() ([]byte, error) { return marshalFields(reflect.ValueOf(c)) }
+func (c *HealthConfig) UnmarshalJSON(data []byte) error {
+	return unmarshalFields(data, reflect.ValueOf(c).Elem())
+}
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	parityType   = reflect.TypeOf(serial.Parity(0))
+	stopBitsType = reflect.TypeOf(serial.StopBits(0))
+)
+
+// marshalFields encodes the fields of a struct in declaration order under
+// their json tag names
+func marshalFields(v reflect.Value) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, ok := jsonName(field)
+		if !ok {
+			continue
+		}
+		value, err := encodeValue(v.Field(i))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(name)
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// encodeValue encodes durations, parity and stop bits by name and anything
+// else with encoding/json
+func encodeValue(v reflect.Value) ([]byte, error) {
+	switch v.Type() {
+	case durationType:
+		return json.Marshal(time.Duration(v.Int()).String())
+	case parityType:
+		if name, ok := parityName(serial.Parity(v.Int())); ok {
+			return json.Marshal(name)
+		}
+	case stopBitsType:
+		if name, ok := stopBitsName(serial.StopBits(v.Int())); ok {
+			return json.Marshal(name)
+		}
+	}
+	return json.Marshal(v.Interface())
+}
+
+// unmarshalFields decodes a JSON object onto the fields of the addressable
+// struct v, leaving fields missing from the object unchanged
+func unmarshalFields(data []byte, v reflect.Value) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(raw))
+	for key := range raw {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		field, ok := jsonField(v.Type(), key)
+		if !ok {
+			return fmt.Errorf("unknown field %q", key)
+		}
+		if err := decodeValue(raw[key], v.FieldByIndex(field.Index)); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// decodeValue decodes durations, parity and stop bits from their names and
+// anything else with encoding/json
+func decodeValue(data json.RawMessage, v reflect.Value) error {
+	if string(bytes.TrimSpace(data)) == "null" {
+		return nil
+	}
+
+	switch v.Type() {
+	case durationType:
+		var text string
+		if err := json.Unmarshal(data, &text); err != nil {
+			return fmt.Errorf("expected a duration string such as \"10s\", got %s", data)
+		}
+		d, err := time.ParseDuration(text)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	case parityType:
+		var text string
+		if err := json.Unmarshal(data, &text); err != nil {
+			return fmt.Errorf("expected none, odd, even, mark or space, got %s", data)
+		}
+		parity, err := ParseParity(text)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(parity))
+		return nil
+	case stopBitsType:
+		// "1.5" and the bare number 1.5 are both accepted
+		var text string
+		if err := json.Unmarshal(data, &text); err != nil {
+			text = strings.TrimSpace(string(data))
+		}
+		stopBits, err := ParseStopBits(text)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(stopBits))
+		return nil
+	}
+	return json.Unmarshal(data, v.Addr().Interface())
+}
+
+// jsonName returns the key a struct field is encoded under
+func jsonName(field reflect.StructField) (string, bool) {
+	if !field.IsExported() {
+		return "", false
+	}
+	tag, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	switch tag {
+	case "-":
+		return "", false
+	case "":
+		return field.Name, true
+	default:
+		return tag, true
+	}
+}
+
+// jsonField finds the struct field a JSON key decodes into, preferring an
+// exact match and otherwise matching case-insensitively like encoding/json
+func jsonField(t reflect.Type, key string) (reflect.StructField, bool) {
+	var fold reflect.StructField
+	found := false
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, ok := jsonName(field)
+		if !ok {
+			continue
+		}
+		if name == key {
+			return field, true
+		}
+		if !found && strings.EqualFold(name, key) {
+			fold, found = field, true
+		}
+	}
+	return fold, found
+}
+
+// parityNames maps the accepted parity names to their values
+var parityNames = map[string]serial.Parity{
+	"none":  serial.NoParity,
+	"odd":   serial.OddParity,
+	"even":  serial.EvenParity,
+	"mark":  serial.MarkParity,
+	"space": serial.SpaceParity,
+}
+
+// ParseParity parses a parity name such as "none" or "even"
+func ParseParity(name string) (serial.Parity, error) {
+	parity, ok := parityNames[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return 0, fmt.Errorf("unknown parity %q, use none, odd, even, mark or space", name)
+	}
+	return parity, nil
+}
+
+func parityName(parity serial.Parity) (string, bool) {
+	for name, p := range parityNames {
+		if p == parity {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// stopBitsNames maps the accepted stop bit counts to their values
+var stopBitsNames = map[string]serial.StopBits{
+	"1":   serial.OneStopBit,
+	"1.5": serial.OnePointFiveStopBits,
+	"2":   serial.TwoStopBits,
+}
+
+// ParseStopBits parses a stop bit count: "1", "1.5" or "2"
+func ParseStopBits(name string) (serial.StopBits, error) {
+	stopBits, ok := stopBitsNames[strings.TrimSpace(name)]
+	if !ok {
+		return 0, fmt.Errorf("unknown stop bits %q, use 1, 1.5 or 2", name)
+	}
+	return stopBits, nil
+}
+
+func stopBitsName(stopBits serial.StopBits) (string, bool) {
+	for name, s := range stopBitsNames {
+		if s == stopBits {
+			return name, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,147 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+func TestSerialEncodingRoundTrip(t *testing.T) {
+	tests := []struct {
+		json   string
+		config SerialBridgeConfig
+	}{
+		{`"parity":"none","stop_bits":"1","timeout":"10s"`, SerialBridgeConfig{Parity: serial.NoParity, StopBits: serial.OneStopBit, Timeout: 10 * time.Second}},
+		{`"parity":"odd","stop_bits":"1.5","timeout":"1m30s"`, SerialBridgeConfig{Parity: serial.OddParity, StopBits: serial.OnePointFiveStopBits, Timeout: 90 * time.Second}},
+		{`"parity":"even","stop_bits":"2","timeout":"250ms"`, SerialBridgeConfig{Parity: serial.EvenParity, StopBits: serial.TwoStopBits, Timeout: 250 * time.Millisecond}},
+		{`"parity":"mark","stop_bits":"1","timeout":"0s"`, SerialBridgeConfig{Parity: serial.MarkParity}},
+		{`"parity":"space","stop_bits":"1","timeout":"1h0m0s"`, SerialBridgeConfig{Parity: serial.SpaceParity, Timeout: time.Hour}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.json, func(t *testing.T) {
+			data, err := json.Marshal(tt.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(data), tt.json) {
+				t.Fatalf("Marshal = %s, want it to contain %s", data, tt.json)
+			}
+
+			var decoded SerialBridgeConfig
+			if err := json.Unmarshal(data, &decoded); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(decoded, tt.config) {
+				t.Fatalf("round trip = %+v, want %+v", decoded, tt.config)
+			}
+		})
+	}
+}
+
+func TestDecodeSerialValues(t *testing.T) {
+	tests := []struct {
+		json    string
+		want    SerialBridgeConfig
+		wantErr string
+	}{
+		{json: `{"parity": "EVEN"}`, want: SerialBridgeConfig{Parity: serial.EvenParity}},
+		{json: `{"parity": " odd "}`, want: SerialBridgeConfig{Parity: serial.OddParity}},
+		{json: `{"stop_bits": 1.5}`, want: SerialBridgeConfig{StopBits: serial.OnePointFiveStopBits}},
+		{json: `{"stop_bits": 2}`, want: SerialBridgeConfig{StopBits: serial.TwoStopBits}},
+		{json: `{"stop_bits": " 2 "}`, want: SerialBridgeConfig{StopBits: serial.TwoStopBits}},
+		{json: `{"timeout": null}`, want: SerialBridgeConfig{}},
+		{json: `{"parity": 2}`, wantErr: "parity: expected none, odd, even, mark or space, got 2"},
+		{json: `{"parity": "sometimes"}`, wantErr: `unknown parity "sometimes"`},
+		{json: `{"stop_bits": 3}`, wantErr: `unknown stop bits "3"`},
+		{json: `{"stop_bits": 1.50}`, wantErr: `unknown stop bits "1.50"`},
+		{json: `{"timeout": 10000000000}`, wantErr: `timeout: expected a duration string such as "10s", got 10000000000`},
+		{json: `{"timeout": "10"}`, wantErr: "missing unit in duration"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.json, func(t *testing.T) {
+			var got SerialBridgeConfig
+			err := json.Unmarshal([]byte(tt.json), &got)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Unmarshal = %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Unmarshal = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnknownKeysAreRejected(t *testing.T) {
+	tests := []struct {
+		json string
+		want string
+	}{
+		{`{"serial_brige": {}}`, `unknown field "serial_brige"`},
+		{`{"serial_bridge": {"baudrate": 9600}}`, `serial_bridge: unknown field "baudrate"`},
+		{`{"serial_bridge": {"devices": [{"port": "COM3"}]}}`, `serial_bridge: devices: unknown field "port"`},
+		{`{"mqtt": {"retained": true}}`, `mqtt: unknown field "retained"`},
+	}
+	for _, tt := range tests {
+		var c Config
+		if err := json.Unmarshal([]byte(tt.json), &c); err == nil || err.Error() != tt.want {
+			t.Errorf("Unmarshal(%s) = %v, want %s", tt.json, err, tt.want)
+		}
+	}
+}
+
+func TestJSONField(t *testing.T) {
+	type fields struct {
+		Exact  string `json:"name"`
+		Folded string `json:"NAME"`
+		Other  string `json:"other_name"`
+		Skip   string `json:"-"`
+		Plain  string
+		hidden string
+	}
+	typ := reflect.TypeOf(fields{})
+
+	tests := []struct {
+		key   string
+		field string // "" when no field matches
+	}{
+		{"name", "Exact"},
+		{"NAME", "Folded"},
+		{"Name", "Exact"}, // the first case-insensitive match wins
+		{"OTHER_NAME", "Other"},
+		{"plain", "Plain"},
+		{"-", ""},
+		{"Skip", ""},
+		{"hidden", ""},
+		{"othername", ""},
+	}
+	for _, tt := range tests {
+		field, ok := jsonField(typ, tt.key)
+		if ok != (tt.field != "") || field.Name != tt.field {
+			t.Errorf("jsonField(%q) = %q, %v, want %q", tt.key, field.Name, ok, tt.field)
+		}
+	}
+}
+
+func TestDecodeKeepsMissingFields(t *testing.T) {
+	c := defaultConfig("production")
+	if err := json.Unmarshal([]byte(`{"serial_bridge": {"baud_rate": 4800, "devices": [{"port_name": "COM3"}]}}`), c); err != nil {
+		t.Fatal(err)
+	}
+	if c.SerialBridge.BaudRate != 4800 || c.SerialBridge.DataBits != 8 || c.SocketConfig.Port != ":8001" {
+		t.Fatalf("partial decode lost defaults: %+v", c.SerialBridge)
+	}
+	// a decoded rule replaces the default one rather than merging into it
+	if want := []DeviceMatchRule{{PortName: "COM3"}}; !reflect.DeepEqual(c.SerialBridge.Devices, want) {
+		t.Fatalf("Devices = %+v, want %+v", c.SerialBridge.Devices, want)
+	}
+}
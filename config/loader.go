@@ -0,0 +1,360 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EnvPrefix prefixes the environment variable of every setting, e.g. BRIDGE_SERIAL_BAUDRATE
+const EnvPrefix = "BRIDGE_SERIAL_"
+
+// Value sources, from lowest to highest precedence
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+)
+
+// setting is a value that can be overridden from the environment and the command line
+type setting struct {
+	name  string // flag name; the environment variable is EnvPrefix + NAME
+	path  string // field path as printed by PrintConfig
+	usage string
+	apply func(c *Config, value string) error
+}
+
+// settings lists the overridable values
+var settings = []setting{
+	{"mode", "app.mode", "mode of the application", setString(func(c *Config) *string { return &c.App.Mode })},
+
+	{"baudrate", "serial_bridge.baud_rate", "serial baud rate", setInt(func(c *Config) *int { return &c.SerialBridge.BaudRate })},
+	{"databits", "serial_bridge.data_bits", "serial data bits", setInt(func(c *Config) *int { return &c.SerialBridge.DataBits })},
+	{"parity", "serial_bridge.parity", "serial parity: none, odd, even, mark or space", setParity},
+	{"stopbits", "serial_bridge.stop_bits", "serial stop bits: 1, 1.5 or 2", setStopBits},
+	{"read-timeout", "serial_bridge.timeout", "serial read timeout", setDuration(func(c *Config) *time.Duration { return &c.SerialBridge.Timeout })},
+	{"serial-port", "serial_bridge.devices", "serial port name, replacing the device match rules", setSerialPort},
+	{"transport", "serial_bridge.transport", "serial transport: serial or tcp", setString(func(c *Config) *string { return &c.SerialBridge.Transport })},
+	{"tcp-address", "serial_bridge.tcp_address", "host:port of a serial device server", setString(func(c *Config) *string { return &c.SerialBridge.TCPAddress })},
+	{"protocol", "serial_bridge.protocol", "scale protocol parser", setString(func(c *Config) *string { return &c.SerialBridge.Protocol })},
+	{"serial-mode", "serial_bridge.mode", "stream or poll", setString(func(c *Config) *string { return &c.SerialBridge.Mode })},
+	{"poll-command", "serial_bridge.poll_command", "command sent to request a weight in poll mode", setString(func(c *Config) *string { return &c.SerialBridge.PollCommand })},
+	{"poll-interval", "serial_bridge.poll_interval", "delay between weight requests in poll mode", setDuration(func(c *Config) *time.Duration { return &c.SerialBridge.PollInterval })},
+
+	{"listen", "socket.port", "HTTP and WebSocket listen address, e.g. :8001", setString(func(c *Config) *string { return &c.SocketConfig.Port })},
+	{"allowed-origins", "socket.allowed_origins", "comma separated browser origins allowed to connect", setList(func(c *Config) *[]string { return &c.SocketConfig.AllowedOrigins })},
+
+	{"auth", "auth.enabled", "require an API key or user credentials", setBool(func(c *Config) *bool { return &c.Auth.Enabled })},
+	{"api-key-file", "auth.api_key_file", "file holding the API key", setString(func(c *Config) *string { return &c.Auth.APIKeyFile })},
+	{"user", "user", "user name for Basic authentication", setString(func(c *Config) *string { return &c.User })},
+	{"password", "password", "password for Basic authentication", setString(func(c *Config) *string { return &c.Password })},
+
+	{"tls", "tls.enabled", "serve https:// and wss://", setBool(func(c *Config) *bool { return &c.TLS.Enabled })},
+	{"tls-cert", "tls.cert_file", "TLS certificate file", setString(func(c *Config) *string { return &c.TLS.CertFile })},
+	{"tls-key", "tls.key_file", "TLS private key file", setString(func(c *Config) *string { return &c.TLS.KeyFile })},
+
+	{"forward", "http_client.enabled", "forward readings to an HTTP endpoint", setBool(func(c *Config) *bool { return &c.HTTPClient.Enabled })},
+	{"forward-url", "http_client.base_url", "base URL readings are forwarded to", setString(func(c *Config) *string { return &c.HTTPClient.BaseURL })},
+	{"forward-path", "http_client.path", "path readings are posted to", setString(func(c *Config) *string { return &c.HTTPClient.Path })},
+	{"forward-token", "http_client.auth_token", "value of the forwarder auth header", setString(func(c *Config) *string { return &c.HTTPClient.AuthToken })},
+
+	{"mqtt", "mqtt.enabled", "publish readings to an MQTT broker", setBool(func(c *Config) *bool { return &c.MQTT.Enabled })},
+	{"mqtt-broker", "mqtt.broker", "MQTT broker address", setString(func(c *Config) *string { return &c.MQTT.Broker })},
+	{"mqtt-client-id", "mqtt.client_id", "MQTT client identifier", setString(func(c *Config) *string { return &c.MQTT.ClientID })},
+	{"mqtt-username", "mqtt.username", "MQTT user name", setString(func(c *Config) *string { return &c.MQTT.Username })},
+	{"mqtt-password", "mqtt.password", "MQTT password", setString(func(c *Config) *string { return &c.MQTT.Password })},
+	{"mqtt-topic", "mqtt.topic_template", "MQTT topic template for readings", setString(func(c *Config) *string { return &c.MQTT.TopicTemplate })},
+}
+
+// secretPaths are masked by PrintConfig
+var secretPaths = map[string]bool{
+	"password":               true,
+	"http_client.auth_token": true,
+	"mqtt.password":          true,
+}
+
+// LoadOptions selects the layers merged by Load
+type LoadOptions struct {
+	// Mode is the default App.Mode, "production" when empty
+	Mode string
+	// File is the config file to read, GetDefaultConfigPath when empty
+	File string
+	// Env holds KEY=value pairs, usually os.Environ()
+	Env []string
+	// Flags maps setting names to the values given on the command line
+	Flags map[string]string
+}
+
+// Loaded is the effective configuration and where each value came from
+type Loaded struct {
+	Config *Config
+	// File is the config file that was read, empty if there was none
+	File string
+	// Sources maps field paths such as "serial_bridge.baud_rate" to the layer
+	// that set them; paths missing from the map hold their default
+	Sources map[string]string
+	// Notes describes what loading found worth logging once the logger is
+	// up, e.g. a file in an older schema that was migrated in memory
+	Notes []string
+}
+
+// Load merges the built-in defaults, the config file, BRIDGE_SERIAL_*
+// environment variables and command-line flags, later layers winning. The
+// file is only read: one in an older schema is migrated in memory and left
+// for UpgradeFile to rewrite.
+func Load(opts LoadOptions) (*Loaded, error) {
+	mode := opts.Mode
+	if mode == "" {
+		mode = "production"
+	}
+	cfg := defaultConfig(mode)
+	loaded := &Loaded{Config: cfg, Sources: make(map[string]string)}
+
+	path := opts.File
+	if path == "" {
+		path = cfg.GetDefaultConfigPath()
+	}
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		migrated, version, err := migrate(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		if version != SchemaVersion {
+			loaded.Notes = append(loaded.Notes, fmt.Sprintf("%s uses schema version %d, run with --upgrade-config to rewrite it in version %d", path, version, SchemaVersion))
+			data = migrated
+		}
+		if err := json.Unmarshal(data, cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		var raw map[string]interface{}
+		if err := json.Unmarshal(data, &raw); err == nil {
+			markFileSources(raw, reflect.TypeOf(*cfg), "", loaded.Sources)
+		}
+		loaded.File = path
+	case errors.Is(err, os.ErrNotExist) && opts.File == "":
+		// running without a config file is fine unless one was asked for
+	default:
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	env := make(map[string]string)
+	for _, pair := range opts.Env {
+		if key, value, ok := strings.Cut(pair, "="); ok && strings.HasPrefix(key, EnvPrefix) {
+			env[key] = value
+		}
+	}
+	for _, s := range settings {
+		name := s.envName()
+		value, ok := env[name]
+		if !ok {
+			continue
+		}
+		if err := s.apply(cfg, value); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", name, err)
+		}
+		loaded.Sources[s.path] = SourceEnv + " " + name
+	}
+
+	for _, s := range settings {
+		value, ok := opts.Flags[s.name]
+		if !ok {
+			continue
+		}
+		if err := s.apply(cfg, value); err != nil {
+			return nil, fmt.Errorf("invalid --%s: %w", s.name, err)
+		}
+		loaded.Sources[s.path] = SourceFlag + " --" + s.name
+	}
+
+	return loaded, nil
+}
+
+func (s setting) envName() string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(s.name, "-", "_"))
+}
+
+// Flags registers a command-line flag for every overridable setting
+type Flags struct {
+	fs     *flag.FlagSet
+	values map[string]*string
+}
+
+// RegisterFlags adds the setting flags to fs
+func RegisterFlags(fs *flag.FlagSet) *Flags {
+	f := &Flags{fs: fs, values: make(map[string]*string)}
+	for _, s := range settings {
+		f.values[s.name] = fs.String(s.name, "", fmt.Sprintf("%s (env %s)", s.usage, s.envName()))
+	}
+	return f
+}
+
+// Values returns the setting flags given on the command line, for LoadOptions.Flags
+func (f *Flags) Values() map[string]string {
+	values := make(map[string]string)
+	f.fs.Visit(func(fl *flag.Flag) {
+		if value, ok := f.values[fl.Name]; ok {
+			values[fl.Name] = *value
+		}
+	})
+	return values
+}
+
+// PrintConfig writes every effective value with its source, masking secrets
+func (l *Loaded) PrintConfig(w io.Writer) {
+	if l.File != "" {
+		fmt.Fprintf(w, "# config file: %s\n", l.File)
+	} else {
+		fmt.Fprintf(w, "# config file: none (looked for %s)\n", l.Config.GetDefaultConfigPath())
+	}
+
+	walkValues(reflect.ValueOf(*l.Config), "", func(path string, value reflect.Value) {
+		text := formatValue(value)
+		if secretPaths[path] && !value.IsZero() {
+			text = `"********"`
+		}
+		fmt.Fprintf(w, "%s = %s  # %s\n", path, text, l.source(path))
+	})
+}
+
+func (l *Loaded) source(path string) string {
+	if source, ok := l.Sources[path]; ok {
+		return source
+	}
+	return SourceDefault
+}
+
+// walkValues calls fn for every non-struct field of v in declaration order,
+// with the dotted path of config.json keys leading to it
+func walkValues(v reflect.Value, prefix string, fn func(path string, value reflect.Value)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name, ok := jsonName(t.Field(i))
+		if !ok {
+			continue
+		}
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+		if t.Field(i).Type.Kind() == reflect.Struct {
+			walkValues(v.Field(i), path, fn)
+			continue
+		}
+		fn(path, v.Field(i))
+	}
+}
+
+// markFileSources records the fields present in a decoded config file
+func markFileSources(raw map[string]interface{}, t reflect.Type, prefix string, sources map[string]string) {
+	for key, value := range raw {
+		field, ok := jsonField(t, key)
+		if !ok {
+			continue
+		}
+		name, _ := jsonName(field)
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+		if nested, ok := value.(map[string]interface{}); ok && field.Type.Kind() == reflect.Struct {
+			markFileSources(nested, field.Type, path, sources)
+			continue
+		}
+		sources[path] = SourceFile
+	}
+}
+
+// formatValue renders a value for PrintConfig as it would appear in config.json
+func formatValue(v reflect.Value) string {
+	data, err := encodeValue(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v.Interface())
+	}
+	return string(data)
+}
+
+func setString(field func(*Config) *string) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		*field(c) = value
+		return nil
+	}
+}
+
+func setInt(field func(*Config) *int) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return err
+		}
+		*field(c) = n
+		return nil
+	}
+}
+
+func setBool(field func(*Config) *bool) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		b, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return err
+		}
+		*field(c) = b
+		return nil
+	}
+}
+
+func setDuration(field func(*Config) *time.Duration) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		d, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return err
+		}
+		*field(c) = d
+		return nil
+	}
+}
+
+func setList(field func(*Config) *[]string) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		var list []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		*field(c) = list
+		return nil
+	}
+}
+
+func setSerialPort(c *Config, value string) error {
+	c.SerialBridge.Devices = []DeviceMatchRule{{PortName: strings.TrimSpace(value)}}
+	return nil
+}
+
+func setParity(c *Config, value string) error {
+	parity, err := ParseParity(value)
+	if err != nil {
+		return err
+	}
+	c.SerialBridge.Parity = parity
+	return nil
+}
+
+func setStopBits(c *Config, value string) error {
+	stopBits, err := ParseStopBits(value)
+	if err != nil {
+		return err
+	}
+	c.SerialBridge.StopBits = stopBits
+	return nil
+}
@@ -0,0 +1,154 @@
+package config
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadPrecedence(t *testing.T) {
+	file := `{"serial_bridge": {"baud_rate": 19200, "timeout": "3s"}, "socket": {"port": ":9001"}, "user": "operator"}`
+
+	tests := []struct {
+		name    string
+		env     []string
+		flags   map[string]string
+		baud    int
+		timeout time.Duration
+		port    string
+		sources map[string]string // "" means the default
+	}{
+		{
+			name:    "file over defaults",
+			baud:    19200,
+			timeout: 3 * time.Second,
+			port:    ":9001",
+			sources: map[string]string{
+				"serial_bridge.baud_rate": SourceFile,
+				"serial_bridge.timeout":   SourceFile,
+				"serial_bridge.data_bits": "",
+				"user":                    SourceFile,
+			},
+		},
+		{
+			name:    "env over file",
+			env:     []string{"BRIDGE_SERIAL_BAUDRATE=38400", "BRIDGE_SERIAL_READ_TIMEOUT=4s", "OTHER_BAUDRATE=300"},
+			baud:    38400,
+			timeout: 4 * time.Second,
+			port:    ":9001",
+			sources: map[string]string{
+				"serial_bridge.baud_rate": "env BRIDGE_SERIAL_BAUDRATE",
+				"serial_bridge.timeout":   "env BRIDGE_SERIAL_READ_TIMEOUT",
+				"socket.port":             SourceFile,
+			},
+		},
+		{
+			name:    "flags over env",
+			env:     []string{"BRIDGE_SERIAL_BAUDRATE=38400", "BRIDGE_SERIAL_LISTEN=:9002"},
+			flags:   map[string]string{"baudrate": "57600"},
+			baud:    57600,
+			timeout: 3 * time.Second,
+			port:    ":9002",
+			sources: map[string]string{
+				"serial_bridge.baud_rate": "flag --baudrate",
+				"socket.port":             "env BRIDGE_SERIAL_LISTEN",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loaded, err := Load(LoadOptions{File: writeConfig(t, file), Env: tt.env, Flags: tt.flags})
+			if err != nil {
+				t.Fatal(err)
+			}
+			cfg := loaded.Config
+			if cfg.SerialBridge.BaudRate != tt.baud || cfg.SerialBridge.Timeout != tt.timeout || cfg.SocketConfig.Port != tt.port {
+				t.Errorf("baud %d timeout %s port %s, want %d %s %s",
+					cfg.SerialBridge.BaudRate, cfg.SerialBridge.Timeout, cfg.SocketConfig.Port, tt.baud, tt.timeout, tt.port)
+			}
+			// untouched values keep their default
+			if cfg.SerialBridge.DataBits != 8 || cfg.App.Mode != "production" {
+				t.Errorf("defaults lost: data bits %d, mode %q", cfg.SerialBridge.DataBits, cfg.App.Mode)
+			}
+			for path, want := range tt.sources {
+				if got := loaded.Sources[path]; got != want {
+					t.Errorf("source of %s = %q, want %q", path, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		file  string
+		env   []string
+		flags map[string]string
+		want  string
+	}{
+		{name: "invalid env value", env: []string{"BRIDGE_SERIAL_BAUDRATE=fast"}, want: "invalid BRIDGE_SERIAL_BAUDRATE"},
+		{name: "invalid flag value", flags: map[string]string{"parity": "sometimes"}, want: "invalid --parity"},
+		{name: "invalid json", file: `{"serial_bridge": `, want: "failed to parse"},
+		{name: "newer schema", file: `{"version": 99}`, want: "newer than the supported version"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := tt.file
+			if file == "" {
+				file = "{}"
+			}
+			_, err := Load(LoadOptions{File: writeConfig(t, file), Env: tt.env, Flags: tt.flags})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Load = %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadWithoutFile(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	// the default file may be missing, an explicit one may not
+	loaded, err := Load(LoadOptions{Mode: "dev"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.File != "" || len(loaded.Sources) != 0 || loaded.Config.App.Mode != "dev" {
+		t.Fatalf("Load without a file = %+v", loaded)
+	}
+	if _, err := Load(LoadOptions{File: filepath.Join(t.TempDir(), "missing.json")}); err == nil {
+		t.Fatal("missing explicit config file was accepted")
+	}
+}
+
+func TestPrintConfigMasksSecrets(t *testing.T) {
+	loaded, err := Load(LoadOptions{
+		File:  writeConfig(t, `{"password": "s3cret"}`),
+		Flags: map[string]string{"mqtt-password": "hunter2"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	loaded.PrintConfig(&out)
+	text := out.String()
+	for _, secret := range []string{"s3cret", "hunter2"} {
+		if strings.Contains(text, secret) {
+			t.Errorf("PrintConfig shows %q", secret)
+		}
+	}
+	for _, line := range []string{
+		`password = "********"  # file`,
+		`mqtt.password = "********"  # flag --mqtt-password`,
+		`http_client.auth_token = ""  # default`,
+		`serial_bridge.parity = "none"  # default`,
+	} {
+		if !strings.Contains(text, line+"\n") {
+			t.Errorf("PrintConfig has no line %q:\n%s", line, text)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+// SchemaVersion is the config.json layout read and written by this version.
+// Version 1 files have no version key, use the Go field names as keys and
+// hold durations in nanoseconds and parity and stop bits as numbers.
+const SchemaVersion = 2
+
+// migrations upgrade a decoded config file from the version they are keyed
+// by to the next one
+var migrations = map[int]func(raw map[string]interface{}) map[string]interface{}{
+	1: migrateV1,
+}
+
+// migrate upgrades a config file to SchemaVersion and returns it with the
+// version it was written in; current files are returned unchanged
+func migrate(data []byte) ([]byte, int, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var raw map[string]interface{}
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, 0, err
+	}
+
+	version := 1
+	if value, ok := raw["version"]; ok {
+		n, ok := value.(json.Number)
+		if !ok {
+			return nil, 0, fmt.Errorf("version must be a number, got %v", value)
+		}
+		v, err := n.Int64()
+		if err != nil || v < 1 {
+			return nil, 0, fmt.Errorf("invalid version %s", n)
+		}
+		version = int(v)
+	}
+	if version > SchemaVersion {
+		return nil, 0, fmt.Errorf("schema version %d is newer than the supported version %d", version, SchemaVersion)
+	}
+	if version == SchemaVersion {
+		return data, version, nil
+	}
+
+	for v := version; v < SchemaVersion; v++ {
+		raw = migrations[v](raw)
+		raw["version"] = v + 1
+	}
+	migrated, err := json.MarshalIndent(raw, "", "  ")
+	if err != nil {
+		return nil, 0, err
+	}
+	return migrated, version, nil
+}
+
+// UpgradeFile rewrites the config file at path in the current schema,
+// keeping the original next to it as <path>.v<version>.bak. It returns the
+// version the file was in; a current file is left untouched.
+func UpgradeFile(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read config file: %w", err)
+	}
+	migrated, version, err := migrate(data)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if version == SchemaVersion {
+		return version, nil
+	}
+	// refuse to write a file the loader would reject
+	if err := json.Unmarshal(migrated, &Config{}); err != nil {
+		return 0, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if err := migrateFile(path, data, migrated, version); err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+// migrateFile replaces a config file with its migrated content, keeping the
+// original next to it as <path>.v<version>.bak
+func migrateFile(path string, original, migrated []byte, version int) error {
+	backup := fmt.Sprintf("%s.v%d.bak", path, version)
+	if err := os.WriteFile(backup, original, 0600); err != nil {
+		return fmt.Errorf("failed to back up config file: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(migrated, '\n'), 0600); err != nil {
+		return fmt.Errorf("failed to write migrated config file: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to replace config file: %w", err)
+	}
+	return nil
+}
+
+// migrateV1 renames Go field names to their json tags and spells out
+// durations, parity and stop bits
+func migrateV1(raw map[string]interface{}) map[string]interface{} {
+	return migrateV1Object(raw, reflect.TypeOf(Config{}))
+}
+
+func migrateV1Object(raw map[string]interface{}, t reflect.Type) map[string]interface{} {
+	out := make(map[string]interface{}, len(raw))
+	for key, value := range raw {
+		field, ok := goField(t, key)
+		if !ok {
+			field, ok = jsonField(t, key)
+		}
+		if !ok {
+			// left for the decoder to report
+			out[key] = value
+			continue
+		}
+		name, _ := jsonName(field)
+		out[name] = migrateV1Value(value, field.Type)
+	}
+	return out
+}
+
+func migrateV1Value(value interface{}, t reflect.Type) interface{} {
+	if n, ok := value.(json.Number); ok {
+		v, err := n.Int64()
+		if err != nil {
+			return value
+		}
+		switch t {
+		case durationType:
+			return time.Duration(v).String()
+		case parityType:
+			if name, ok := parityName(serial.Parity(v)); ok {
+				return name
+			}
+		case stopBitsType:
+			if name, ok := stopBitsName(serial.StopBits(v)); ok {
+				return name
+			}
+		}
+		return value
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if object, ok := value.(map[string]interface{}); ok {
+			return migrateV1Object(object, t)
+		}
+	case reflect.Slice:
+		if items, ok := value.([]interface{}); ok {
+			for i, item := range items {
+				items[i] = migrateV1Value(item, t.Elem())
+			}
+		}
+	}
+	return value
+}
+
+// goField finds a struct field by its Go name, ignoring case as version 1 did
+func goField(t reflect.Type, key string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.IsExported() && strings.EqualFold(field.Name, key) {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
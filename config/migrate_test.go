@@ -0,0 +1,191 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+// v1File is a config.json as written before schema versions: Go field
+// names, durations in nanoseconds and numeric parity and stop bits
+const v1File = `{
+  "SerialBridge": {
+    "BaudRate": 19200,
+    "Parity": 2,
+    "StopBits": 2,
+    "Timeout": 3000000000,
+    "Devices": [
+      {"Priority": 1, "VID": "0403", "PID": "6001"},
+      {"portname": "COM3"}
+    ]
+  },
+  "HTTPClient": {"Enabled": true, "BaseURL": "http://backend:8080", "RetryBackoff": 2000000000},
+  "SocketConfig": {"Port": ":9001"},
+  "User": "operator"
+}`
+
+// writeConfig writes data to config.json in a temporary directory
+func writeConfig(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadDoesNotRewriteOldFile(t *testing.T) {
+	path := writeConfig(t, v1File)
+
+	loaded, err := Load(LoadOptions{File: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Config.SerialBridge.BaudRate != 19200 || loaded.Config.SerialBridge.Timeout != 3*time.Second {
+		t.Fatalf("v1 file was not migrated in memory: %+v", loaded.Config.SerialBridge)
+	}
+	if len(loaded.Notes) != 1 || !strings.Contains(loaded.Notes[0], "--upgrade-config") {
+		t.Fatalf("Notes = %q, want a hint to upgrade", loaded.Notes)
+	}
+
+	if data, _ := os.ReadFile(path); string(data) != v1File {
+		t.Fatalf("Load rewrote the config file:\n%s", data)
+	}
+	if backups, _ := filepath.Glob(path + ".*"); len(backups) != 0 {
+		t.Fatalf("Load wrote %v", backups)
+	}
+}
+
+func TestUpgradeFile(t *testing.T) {
+	path := writeConfig(t, v1File)
+
+	version, err := UpgradeFile(path)
+	if err != nil || version != 1 {
+		t.Fatalf("UpgradeFile = %d, %v, want 1", version, err)
+	}
+	if backup, err := os.ReadFile(path + ".v1.bak"); err != nil || string(backup) != v1File {
+		t.Fatalf("backup = %q, %v, want the original file", backup, err)
+	}
+
+	upgraded, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(LoadOptions{File: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Notes) != 0 {
+		t.Fatalf("upgraded file still needs migrating: %q", loaded.Notes)
+	}
+	sb := loaded.Config.SerialBridge
+	if sb.BaudRate != 19200 || sb.Parity != serial.EvenParity || sb.StopBits != serial.TwoStopBits || len(sb.Devices) != 2 {
+		t.Fatalf("upgraded serial settings = %+v", sb)
+	}
+
+	// a current file is left alone
+	version, err = UpgradeFile(path)
+	if err != nil || version != SchemaVersion {
+		t.Fatalf("second UpgradeFile = %d, %v, want %d", version, err, SchemaVersion)
+	}
+	if after, _ := os.ReadFile(path); !bytes.Equal(after, upgraded) {
+		t.Fatal("current file was rewritten")
+	}
+}
+
+func TestMigrateV1(t *testing.T) {
+	migrated, version, err := migrate([]byte(v1File))
+	if err != nil || version != 1 {
+		t.Fatalf("migrate = %d, %v, want version 1", version, err)
+	}
+
+	var raw map[string]interface{}
+	if err := json.Unmarshal(migrated, &raw); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"version": float64(SchemaVersion),
+		"serial_bridge": map[string]interface{}{
+			"baud_rate": float64(19200),
+			"parity":    "even",
+			"stop_bits": "2",
+			"timeout":   "3s",
+			"devices": []interface{}{
+				map[string]interface{}{"priority": float64(1), "vid": "0403", "pid": "6001"},
+				map[string]interface{}{"port_name": "COM3"},
+			},
+		},
+		"http_client": map[string]interface{}{"enabled": true, "base_url": "http://backend:8080", "retry_backoff": "2s"},
+		"socket":      map[string]interface{}{"port": ":9001"},
+		"user":        "operator",
+	}
+	if !reflect.DeepEqual(raw, want) {
+		t.Fatalf("migrated file =\n%s\nwant %v", migrated, want)
+	}
+
+	var c Config
+	if err := json.Unmarshal(migrated, &c); err != nil {
+		t.Fatalf("migrated file does not decode: %v", err)
+	}
+}
+
+func TestMigrateV1Values(t *testing.T) {
+	tests := []struct {
+		name string
+		v1   string
+		want string // the migrated serial_bridge object
+	}{
+		{"one and a half stop bits", `{"StopBits": 1}`, `{"stop_bits":"1.5"}`},
+		{"no parity", `{"Parity": 0}`, `{"parity":"none"}`},
+		{"unknown parity is kept for the decoder", `{"Parity": 9}`, `{"parity":9}`},
+		{"fractional duration is kept", `{"Timeout": 1.5}`, `{"timeout":1.5}`},
+		{"json keys are kept", `{"baud_rate": 4800, "Timeout": 1000000}`, `{"baud_rate":4800,"timeout":"1ms"}`},
+		{"unknown keys are kept", `{"Baud": 4800}`, `{"Baud":4800}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrated, _, err := migrate([]byte(`{"SerialBridge": ` + tt.v1 + `}`))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var raw map[string]json.RawMessage
+			if err := json.Unmarshal(migrated, &raw); err != nil {
+				t.Fatal(err)
+			}
+			var got bytes.Buffer
+			if err := json.Compact(&got, raw["serial_bridge"]); err != nil {
+				t.Fatal(err)
+			}
+			if got.String() != tt.want {
+				t.Fatalf("migrated serial_bridge = %s, want %s", got.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestMigrateVersions(t *testing.T) {
+	current := `{"version": 2, "serial_bridge": {"baud_rate": 4800}}`
+	migrated, version, err := migrate([]byte(current))
+	if err != nil || version != SchemaVersion || string(migrated) != current {
+		t.Fatalf("migrate(current) = %s, %d, %v, want the file unchanged", migrated, version, err)
+	}
+
+	for data, want := range map[string]string{
+		`{"version": "2"}`: "version must be a number",
+		`{"version": 0}`:   "invalid version 0",
+		`{"version": 1.5}`: "invalid version 1.5",
+		`{"version": 3}`:   "schema version 3 is newer than the supported version 2",
+		`[]`:               "cannot unmarshal array",
+	} {
+		if _, _, err := migrate([]byte(data)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("migrate(%s) = %v, want an error containing %q", data, err, want)
+		}
+	}
+}
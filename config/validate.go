@@ -0,0 +1,296 @@
+package config
+
+import (
+	"bridge-serial/internal/protocol"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BaudRates are the baud rates accepted for SerialBridgeConfig.BaudRate
+var BaudRates = []int{300, 600, 1200, 2400, 4800, 9600, 14400, 19200, 38400, 57600, 115200, 230400, 460800, 921600}
+
+// FieldError is one problem found by Validate
+type FieldError struct {
+	Field   string // path as printed by PrintConfig, e.g. serial_bridge.baud_rate
+	Problem string
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Problem
+}
+
+// problems collects FieldErrors for errors.Join
+type problems []error
+
+func (p *problems) add(field, format string, args ...interface{}) {
+	*p = append(*p, &FieldError{Field: field, Problem: fmt.Sprintf(format, args...)})
+}
+
+// positive reports a duration that has to be greater than zero
+func (p *problems) positive(field string, d time.Duration) {
+	if d <= 0 {
+		p.add(field, "must be a positive duration, got %s", d)
+	}
+}
+
+// notNegative reports a duration below zero, where zero disables a feature
+func (p *problems) notNegative(field string, d time.Duration) {
+	if d < 0 {
+		p.add(field, "must not be negative, got %s", d)
+	}
+}
+
+// Validate checks every setting and returns all problems found joined with
+// errors.Join, one FieldError per line, or nil when the config is usable
+func (c *Config) Validate() error {
+	var p problems
+
+	if strings.TrimSpace(c.App.AppName) == "" {
+		p.add("app.app_name", "must not be empty, it names the config directory")
+	}
+
+	c.SerialBridge.validate(&p)
+	c.HTTPClient.validate(&p)
+	c.MQTT.validate(&p)
+	c.SocketConfig.validate(&p)
+
+	if c.Auth.Enabled && strings.TrimSpace(c.Auth.APIKeyFile) == "" {
+		p.add("auth.api_key_file", "must be set when authentication is enabled")
+	}
+
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		p.add("tls.cert_file", "set both cert_file and key_file, or neither to generate a certificate")
+	}
+	if c.TLS.Enabled && c.TLS.CertFile == "" && strings.TrimSpace(c.TLS.CertDir) == "" {
+		p.add("tls.cert_dir", "must be set to generate a certificate when no cert_file is given")
+	}
+
+	p.notNegative("health.max_reading_age", c.Health.MaxReadingAge)
+	if c.Health.MaxParseErrorRate < 0 || c.Health.MaxParseErrorRate > 1 {
+		p.add("health.max_parse_error_rate", "must be between 0 and 1, got %g", c.Health.MaxParseErrorRate)
+	}
+	if c.Health.MaxForwarderBacklog < 0 {
+		p.add("health.max_forwarder_backlog", "must not be negative, got %d", c.Health.MaxForwarderBacklog)
+	}
+
+	return errors.Join(p...)
+}
+
+// Validate checks the serial settings alone, e.g. before applying an edit
+func (c *SerialBridgeConfig) Validate() error {
+	var p problems
+	c.validate(&p)
+	return errors.Join(p...)
+}
+
+func (c *SerialBridgeConfig) validate(p *problems) {
+	if !validBaudRate(c.BaudRate) {
+		p.add("serial_bridge.baud_rate", "%d is not a supported baud rate, use one of %v", c.BaudRate, BaudRates)
+	}
+	if c.DataBits < 5 || c.DataBits > 8 {
+		p.add("serial_bridge.data_bits", "must be between 5 and 8, got %d", c.DataBits)
+	}
+	if !validParity(c) {
+		p.add("serial_bridge.parity", "unknown parity %d, use none, odd, even, mark or space", c.Parity)
+	}
+	if !validStopBits(c) {
+		p.add("serial_bridge.stop_bits", "unknown stop bits %d, use 1, 1.5 or 2", c.StopBits)
+	}
+	p.positive("serial_bridge.timeout", c.Timeout)
+
+	switch c.Transport {
+	case "", "serial":
+	case "tcp":
+		if err := checkHostPort(c.TCPAddress, true); err != nil {
+			p.add("serial_bridge.tcp_address", "%v", err)
+		}
+	default:
+		p.add("serial_bridge.transport", "unknown transport %q, use serial or tcp", c.Transport)
+	}
+
+	parser, err := protocol.New(c.Protocol)
+	if err != nil {
+		p.add("serial_bridge.protocol", "%v", err)
+	}
+
+	switch c.Mode {
+	case "stream":
+	case "poll":
+		p.positive("serial_bridge.poll_interval", c.PollInterval)
+		p.positive("serial_bridge.response_timeout", c.ResponseTimeout)
+		if _, ok := parser.(protocol.Commander); parser != nil && !ok && c.PollCommand == "" {
+			p.add("serial_bridge.poll_command", "must be set, protocol %s has no weigh command", c.Protocol)
+		}
+	default:
+		p.add("serial_bridge.mode", "unknown mode %q, use stream or poll", c.Mode)
+	}
+
+	p.positive("serial_bridge.reconnect_interval", c.ReconnectInterval)
+	if c.MaxReconnectInterval < c.ReconnectInterval {
+		p.add("serial_bridge.max_reconnect_interval", "must not be below reconnect_interval (%s), got %s", c.ReconnectInterval, c.MaxReconnectInterval)
+	}
+
+	if len(c.Devices) == 0 {
+		p.add("serial_bridge.devices", "must list at least one device rule")
+	}
+	for i, rule := range c.Devices {
+		field := fmt.Sprintf("serial_bridge.devices[%d]", i)
+		if rule.PortName == "" && rule.VID == "" && rule.PID == "" && rule.SerialNumber == "" && rule.Product == "" {
+			p.add(field, "must set at least one of port_name, vid, pid, serial_number or product")
+		}
+		if rule.VID != "" && !validUSBID(rule.VID) {
+			p.add(field+".vid", "%q is not a 4 digit hex USB vendor ID", rule.VID)
+		}
+		if rule.PID != "" && !validUSBID(rule.PID) {
+			p.add(field+".pid", "%q is not a 4 digit hex USB product ID", rule.PID)
+		}
+		if _, err := path.Match(rule.Product, ""); err != nil {
+			p.add(field+".product", "invalid glob %q: %v", rule.Product, err)
+		}
+	}
+}
+
+func (c *HTTPClientConfig) validate(p *problems) {
+	if c.Enabled || c.BaseURL != "" {
+		if err := checkHTTPURL(c.BaseURL); err != nil {
+			p.add("http_client.base_url", "%v", err)
+		}
+	}
+	if c.Path != "" && !strings.HasPrefix(c.Path, "/") {
+		p.add("http_client.path", "must start with /, got %q", c.Path)
+	}
+	if c.AuthToken != "" && strings.TrimSpace(c.AuthHeader) == "" {
+		p.add("http_client.auth_header", "must be set when auth_token is")
+	}
+	p.positive("http_client.timeout", c.Timeout)
+	if c.MaxRetries < 0 {
+		p.add("http_client.max_retries", "must not be negative, got %d", c.MaxRetries)
+	}
+	p.positive("http_client.retry_backoff", c.RetryBackoff)
+	if c.MaxBackoff < c.RetryBackoff {
+		p.add("http_client.max_backoff", "must not be below retry_backoff (%s), got %s", c.RetryBackoff, c.MaxBackoff)
+	}
+	if c.QueueSize <= 0 {
+		p.add("http_client.queue_size", "must be positive, got %d", c.QueueSize)
+	}
+	if c.Durable && strings.TrimSpace(c.QueueDir) == "" {
+		p.add("http_client.queue_dir", "must be set when durable is")
+	}
+	if c.MaxQueueBytes < 0 {
+		p.add("http_client.max_queue_bytes", "must not be negative, got %d", c.MaxQueueBytes)
+	}
+	p.notNegative("http_client.max_queue_age", c.MaxQueueAge)
+}
+
+func (c *MQTTConfig) validate(p *problems) {
+	p.notNegative("mqtt.keep_alive", c.KeepAlive)
+	if c.KeepAlive > 65535*time.Second {
+		p.add("mqtt.keep_alive", "must not exceed 18h12m15s, got %s", c.KeepAlive)
+	}
+	if !c.Enabled {
+		return
+	}
+	if err := checkHostPort(strings.TrimPrefix(c.Broker, "tcp://"), true); err != nil {
+		p.add("mqtt.broker", "%v", err)
+	}
+	if strings.TrimSpace(c.ClientID) == "" {
+		p.add("mqtt.client_id", "must not be empty")
+	}
+	if c.TopicTemplate == "" {
+		p.add("mqtt.topic_template", "must not be empty")
+	} else if strings.ContainsAny(c.TopicTemplate, "+#") {
+		p.add("mqtt.topic_template", "must not contain the wildcards + or #, got %q", c.TopicTemplate)
+	}
+	if strings.ContainsAny(c.StatusTopic, "+#") {
+		p.add("mqtt.status_topic", "must not contain the wildcards + or #, got %q", c.StatusTopic)
+	}
+}
+
+func (c *SocketConfig) validate(p *problems) {
+	if err := checkHostPort(c.Port, false); err != nil {
+		p.add("socket.port", "%v, e.g. :8001", err)
+	}
+	p.positive("socket.retry_interval", c.RetryInterval)
+	for i, origin := range c.AllowedOrigins {
+		if strings.TrimSpace(origin) == "" {
+			p.add(fmt.Sprintf("socket.allowed_origins[%d]", i), "must not be empty")
+		}
+	}
+	if c.EventHistory < 0 {
+		p.add("socket.event_history", "must not be negative, got %d", c.EventHistory)
+	}
+}
+
+func validBaudRate(rate int) bool {
+	for _, r := range BaudRates {
+		if r == rate {
+			return true
+		}
+	}
+	return false
+}
+
+func validParity(c *SerialBridgeConfig) bool {
+	for _, parity := range parityNames {
+		if parity == c.Parity {
+			return true
+		}
+	}
+	return false
+}
+
+func validStopBits(c *SerialBridgeConfig) bool {
+	for _, stopBits := range stopBitsNames {
+		if stopBits == c.StopBits {
+			return true
+		}
+	}
+	return false
+}
+
+// validUSBID reports whether id is a 4 digit hex USB vendor or product ID
+func validUSBID(id string) bool {
+	if len(id) != 4 {
+		return false
+	}
+	_, err := strconv.ParseUint(id, 16, 16)
+	return err == nil
+}
+
+// checkHostPort checks a host:port address with a port between 1 and 65535,
+// the host being optional unless requireHost is set
+func checkHostPort(address string, requireHost bool) error {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("%q is not a host:port address", address)
+	}
+	if requireHost && host == "" {
+		return fmt.Errorf("%q has no host", address)
+	}
+	n, err := strconv.Atoi(port)
+	if err != nil || n < 1 || n > 65535 {
+		return fmt.Errorf("port %q in %q must be a number between 1 and 65535", port, address)
+	}
+	return nil
+}
+
+// checkHTTPURL checks for an absolute http:// or https:// URL
+func checkHTTPURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid URL: %v", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%q must start with http:// or https://", raw)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("%q has no host", raw)
+	}
+	return nil
+}
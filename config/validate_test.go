@@ -0,0 +1,165 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDefaultConfigIsValid(t *testing.T) {
+	if err := defaultConfig("production").Validate(); err != nil {
+		t.Fatalf("defaults do not validate:\n%v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		field   string
+		problem string
+		mutate  func(c *Config)
+	}{
+		{"app.app_name", "must not be empty", func(c *Config) { c.App.AppName = " " }},
+
+		{"serial_bridge.baud_rate", "1234 is not a supported baud rate", func(c *Config) { c.SerialBridge.BaudRate = 1234 }},
+		{"serial_bridge.data_bits", "must be between 5 and 8, got 9", func(c *Config) { c.SerialBridge.DataBits = 9 }},
+		{"serial_bridge.parity", "unknown parity 7", func(c *Config) { c.SerialBridge.Parity = 7 }},
+		{"serial_bridge.stop_bits", "unknown stop bits 5", func(c *Config) { c.SerialBridge.StopBits = 5 }},
+		{"serial_bridge.timeout", "must be a positive duration, got 0s", func(c *Config) { c.SerialBridge.Timeout = 0 }},
+		{"serial_bridge.transport", `unknown transport "udp"`, func(c *Config) { c.SerialBridge.Transport = "udp" }},
+		{"serial_bridge.tcp_address", `"scale" is not a host:port address`, func(c *Config) {
+			c.SerialBridge.Transport = "tcp"
+			c.SerialBridge.TCPAddress = "scale"
+		}},
+		{"serial_bridge.tcp_address", `":4001" has no host`, func(c *Config) {
+			c.SerialBridge.Transport = "tcp"
+			c.SerialBridge.TCPAddress = ":4001"
+		}},
+		{"serial_bridge.protocol", "nonsense", func(c *Config) { c.SerialBridge.Protocol = "nonsense" }},
+		{"serial_bridge.mode", `unknown mode "push"`, func(c *Config) { c.SerialBridge.Mode = "push" }},
+		{"serial_bridge.poll_interval", "must be a positive duration", func(c *Config) {
+			c.SerialBridge.Mode = "poll"
+			c.SerialBridge.Protocol = "mt-sics"
+			c.SerialBridge.PollInterval = 0
+		}},
+		{"serial_bridge.response_timeout", "must be a positive duration", func(c *Config) {
+			c.SerialBridge.Mode = "poll"
+			c.SerialBridge.Protocol = "mt-sics"
+			c.SerialBridge.ResponseTimeout = -time.Second
+		}},
+		{"serial_bridge.poll_command", "protocol whitespace-fields has no weigh command", func(c *Config) { c.SerialBridge.Mode = "poll" }},
+		{"serial_bridge.reconnect_interval", "must be a positive duration", func(c *Config) { c.SerialBridge.ReconnectInterval = 0 }},
+		{"serial_bridge.max_reconnect_interval", "must not be below reconnect_interval (1s), got 500ms", func(c *Config) {
+			c.SerialBridge.MaxReconnectInterval = 500 * time.Millisecond
+		}},
+		{"serial_bridge.devices", "must list at least one device rule", func(c *Config) { c.SerialBridge.Devices = nil }},
+		{"serial_bridge.devices[1]", "must set at least one of", func(c *Config) { c.SerialBridge.Devices[1] = DeviceMatchRule{Priority: 2} }},
+		{"serial_bridge.devices[0].vid", `"67B" is not a 4 digit hex USB vendor ID`, func(c *Config) { c.SerialBridge.Devices[0].VID = "67B" }},
+		{"serial_bridge.devices[0].pid", `"23G3" is not a 4 digit hex USB product ID`, func(c *Config) { c.SerialBridge.Devices[0].PID = "23G3" }},
+		{"serial_bridge.devices[0].product", `invalid glob "[FT232"`, func(c *Config) { c.SerialBridge.Devices[0].Product = "[FT232" }},
+
+		{"http_client.base_url", `"backend:8080" must start with http:// or https://`, func(c *Config) { c.HTTPClient.BaseURL = "backend:8080" }},
+		{"http_client.base_url", `"http://" has no host`, func(c *Config) { c.HTTPClient.BaseURL = "http://" }},
+		{"http_client.path", `must start with /, got "readings"`, func(c *Config) { c.HTTPClient.Path = "readings" }},
+		{"http_client.auth_header", "must be set when auth_token is", func(c *Config) {
+			c.HTTPClient.AuthToken = "token"
+			c.HTTPClient.AuthHeader = ""
+		}},
+		{"http_client.timeout", "must be a positive duration", func(c *Config) { c.HTTPClient.Timeout = 0 }},
+		{"http_client.max_retries", "must not be negative, got -1", func(c *Config) { c.HTTPClient.MaxRetries = -1 }},
+		{"http_client.retry_backoff", "must be a positive duration", func(c *Config) { c.HTTPClient.RetryBackoff = 0 }},
+		{"http_client.max_backoff", "must not be below retry_backoff (1s), got 100ms", func(c *Config) { c.HTTPClient.MaxBackoff = 100 * time.Millisecond }},
+		{"http_client.queue_size", "must be positive, got 0", func(c *Config) { c.HTTPClient.QueueSize = 0 }},
+		{"http_client.queue_dir", "must be set when durable is", func(c *Config) { c.HTTPClient.QueueDir = "" }},
+		{"http_client.max_queue_bytes", "must not be negative", func(c *Config) { c.HTTPClient.MaxQueueBytes = -1 }},
+		{"http_client.max_queue_age", "must not be negative, got -1h0m0s", func(c *Config) { c.HTTPClient.MaxQueueAge = -time.Hour }},
+
+		{"mqtt.keep_alive", "must not be negative", func(c *Config) { c.MQTT.KeepAlive = -time.Second }},
+		{"mqtt.keep_alive", "must not exceed 18h12m15s", func(c *Config) { c.MQTT.KeepAlive = 24 * time.Hour }},
+		{"mqtt.broker", `"localhost" is not a host:port address`, func(c *Config) {
+			c.MQTT.Enabled = true
+			c.MQTT.Broker = "tcp://localhost"
+		}},
+		{"mqtt.client_id", "must not be empty", func(c *Config) {
+			c.MQTT.Enabled = true
+			c.MQTT.ClientID = ""
+		}},
+		{"mqtt.topic_template", "must not be empty", func(c *Config) {
+			c.MQTT.Enabled = true
+			c.MQTT.TopicTemplate = ""
+		}},
+		{"mqtt.topic_template", "must not contain the wildcards", func(c *Config) {
+			c.MQTT.Enabled = true
+			c.MQTT.TopicTemplate = "scales/+/weight"
+		}},
+		{"mqtt.status_topic", "must not contain the wildcards", func(c *Config) {
+			c.MQTT.Enabled = true
+			c.MQTT.StatusTopic = "scales/#"
+		}},
+
+		{"socket.port", `"8001" is not a host:port address, e.g. :8001`, func(c *Config) { c.SocketConfig.Port = "8001" }},
+		{"socket.port", `port "70000" in ":70000" must be a number between 1 and 65535`, func(c *Config) { c.SocketConfig.Port = ":70000" }},
+		{"socket.retry_interval", "must be a positive duration", func(c *Config) { c.SocketConfig.RetryInterval = 0 }},
+		{"socket.allowed_origins[1]", "must not be empty", func(c *Config) { c.SocketConfig.AllowedOrigins[1] = " " }},
+		{"socket.event_history", "must not be negative", func(c *Config) { c.SocketConfig.EventHistory = -1 }},
+
+		{"auth.api_key_file", "must be set when authentication is enabled", func(c *Config) { c.Auth.APIKeyFile = "" }},
+		{"tls.cert_file", "set both cert_file and key_file", func(c *Config) { c.TLS.CertFile = "bridge.pem" }},
+		{"tls.cert_dir", "must be set to generate a certificate", func(c *Config) {
+			c.TLS.Enabled = true
+			c.TLS.CertDir = ""
+		}},
+
+		{"health.max_reading_age", "must not be negative", func(c *Config) { c.Health.MaxReadingAge = -time.Second }},
+		{"health.max_parse_error_rate", "must be between 0 and 1, got 1.5", func(c *Config) { c.Health.MaxParseErrorRate = 1.5 }},
+		{"health.max_forwarder_backlog", "must not be negative, got -1", func(c *Config) { c.Health.MaxForwarderBacklog = -1 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.field+" "+tt.problem, func(t *testing.T) {
+			c := defaultConfig("production")
+			tt.mutate(c)
+
+			err := c.Validate()
+			if err == nil {
+				t.Fatal("Validate accepted the config")
+			}
+			if strings.Count(err.Error(), "\n") != 0 {
+				t.Fatalf("want exactly one problem, got:\n%v", err)
+			}
+			var fieldErr *FieldError
+			if !errors.As(err, &fieldErr) {
+				t.Fatalf("%v is not a FieldError", err)
+			}
+			if fieldErr.Field != tt.field || !strings.Contains(fieldErr.Problem, tt.problem) {
+				t.Fatalf("Validate = %q, want %s: ...%s...", err, tt.field, tt.problem)
+			}
+		})
+	}
+}
+
+func TestValidateListsEveryProblem(t *testing.T) {
+	c := defaultConfig("production")
+	c.SerialBridge.BaudRate = 0
+	c.SerialBridge.DataBits = 4
+	c.SocketConfig.Port = "8001"
+
+	err := c.Validate()
+	if err == nil {
+		t.Fatal("Validate accepted the config")
+	}
+	lines := strings.Split(err.Error(), "\n")
+	want := []string{"serial_bridge.baud_rate: ", "serial_bridge.data_bits: ", "socket.port: "}
+	if len(lines) != len(want) {
+		t.Fatalf("Validate =\n%v\nwant %d problems", err, len(want))
+	}
+	for i, prefix := range want {
+		if !strings.HasPrefix(lines[i], prefix) {
+			t.Errorf("problem %d = %q, want it to start with %q", i, lines[i], prefix)
+		}
+	}
+
+	// the serial settings validate alone, as before applying an edit
+	if err := c.SerialBridge.Validate(); err == nil || strings.Contains(err.Error(), "socket.port") {
+		t.Fatalf("SerialBridgeConfig.Validate = %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"bridge-serial/pkg/logger"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// reloadDelay collapses the burst of events an editor produces when saving
+// into a single reload
+const reloadDelay = 250 * time.Millisecond
+
+// Watcher reports changes to a config file
+type Watcher struct {
+	watcher *fsnotify.Watcher
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// Watch calls onChange after the file at path is written or replaced. The
+// directory is watched rather than the file so editors that save by renaming
+// a temporary file over it are noticed as well. A removed file is ignored,
+// the running config is kept until a new file appears.
+func Watch(path string, onChange func()) (*Watcher, error) {
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create config watcher: %w", err)
+	}
+	dir := filepath.Dir(path)
+	if err := fw.Add(dir); err != nil {
+		fw.Close()
+		return nil, fmt.Errorf("failed to watch %s: %w", dir, err)
+	}
+
+	w := &Watcher{watcher: fw, done: make(chan struct{})}
+	w.wg.Add(1)
+	go w.run(filepath.Clean(path), onChange)
+	logger.Info("watching %s for changes", path)
+	return w, nil
+}
+
+// Close stops watching; a pending reload is dropped
+func (w *Watcher) Close() error {
+	close(w.done)
+	err := w.watcher.Close()
+	w.wg.Wait()
+	return err
+}
+
+func (w *Watcher) run(path string, onChange func()) {
+	defer w.wg.Done()
+
+	timer := time.NewTimer(reloadDelay)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			if filepath.Clean(event.Name) != path || !event.Has(fsnotify.Write|fsnotify.Create) {
+				continue
+			}
+			timer.Reset(reloadDelay)
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			logger.Error("config watcher error: %v", err)
+		case <-timer.C:
+			onChange()
+		}
+	}
+}
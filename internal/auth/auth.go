@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"bridge-serial/pkg/logger"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SubprotocolBearer is the WebSocket subprotocol browsers send before the
+// token, e.g. new WebSocket(url, ["bearer", token])
+const SubprotocolBearer = "bearer"
+
+// Authenticator validates requests against the API key and the configured user credentials
+type Authenticator struct {
+	apiKey   string
+	user     string
+	password string
+}
+
+// New creates an authenticator. Basic auth is only accepted when user is non-empty.
+func New(apiKey, user, password string) *Authenticator {
+	return &Authenticator{
+		apiKey:   apiKey,
+		user:     user,
+		password: password,
+	}
+}
+
+// LoadOrCreateAPIKey reads the API key stored at path, generating and
+// persisting a new random key if the file does not exist
+func LoadOrCreateAPIKey(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		key := strings.TrimSpace(string(data))
+		if key == "" {
+			return "", fmt.Errorf("API key file %s is empty", path)
+		}
+		return key, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("failed to read API key: %w", err)
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+	key := hex.EncodeToString(buf)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("failed to create API key directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(key+"\n"), 0600); err != nil {
+		return "", fmt.Errorf("failed to write API key: %w", err)
+	}
+	logger.Info("generated new API key in %s", path)
+	return key, nil
+}
+
+// Authenticate reports whether the request carries a valid token, taken from
+// the token query parameter, an Authorization Bearer or Basic header, or the
+// Sec-WebSocket-Protocol header
+func (a *Authenticator) Authenticate(r *http.Request) bool {
+	if token := r.URL.Query().Get("token"); token != "" {
+		return a.validToken(token)
+	}
+
+	if header := r.Header.Get("Authorization"); header != "" {
+		if token, ok := strings.CutPrefix(header, "Bearer "); ok {
+			return a.validToken(strings.TrimSpace(token))
+		}
+		if user, password, ok := r.BasicAuth(); ok {
+			return a.validCredentials(user, password)
+		}
+		return false
+	}
+
+	for _, protocol := range websocketProtocols(r) {
+		if protocol != SubprotocolBearer && a.validToken(protocol) {
+			return true
+		}
+	}
+	return false
+}
+
+// Middleware rejects unauthenticated requests with 401 and logs the failure
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !a.Authenticate(r) {
+			logger.Warn("authentication failed for %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="bridge-serial"`)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "unauthorized"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (a *Authenticator) validToken(token string) bool {
+	return a.apiKey != "" && subtle.ConstantTimeCompare([]byte(token), []byte(a.apiKey)) == 1
+}
+
+func (a *Authenticator) validCredentials(user, password string) bool {
+	if a.user == "" {
+		return false
+	}
+	userOK := subtle.ConstantTimeCompare([]byte(user), []byte(a.user)) == 1
+	passwordOK := subtle.ConstantTimeCompare([]byte(password), []byte(a.password)) == 1
+	return userOK && passwordOK
+}
+
+// websocketProtocols returns the subprotocols requested in Sec-WebSocket-Protocol
+func websocketProtocols(r *http.Request) []string {
+	var protocols []string
+	for _, header := range r.Header.Values("Sec-WebSocket-Protocol") {
+		for _, protocol := range strings.Split(header, ",") {
+			if protocol = strings.TrimSpace(protocol); protocol != "" {
+				protocols = append(protocols, protocol)
+			}
+		}
+	}
+	return protocols
+}
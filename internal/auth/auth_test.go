@@ -0,0 +1,140 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testKey = "0123456789abcdef"
+
+func TestAuthenticate(t *testing.T) {
+	a := New(testKey, "operator", "s3cret")
+
+	tests := []struct {
+		name   string
+		query  string
+		header http.Header
+		want   bool
+	}{
+		{name: "no credentials"},
+		{name: "query token", query: "token=" + testKey, want: true},
+		{name: "wrong query token", query: "token=wrong"},
+		{name: "bearer", header: http.Header{"Authorization": {"Bearer " + testKey}}, want: true},
+		{name: "bearer with padding", header: http.Header{"Authorization": {"Bearer  " + testKey + " "}}, want: true},
+		{name: "wrong bearer", header: http.Header{"Authorization": {"Bearer wrong"}}},
+		{name: "key prefix", header: http.Header{"Authorization": {"Bearer " + testKey[:len(testKey)-1]}}},
+		{name: "key with suffix", header: http.Header{"Authorization": {"Bearer " + testKey + "0"}}},
+		{name: "basic", header: basic("operator", "s3cret"), want: true},
+		{name: "basic wrong password", header: basic("operator", "wrong")},
+		{name: "basic wrong user", header: basic("admin", "s3cret")},
+		{name: "basic with the API key as password", header: basic("operator", testKey)},
+		{name: "unknown scheme", header: http.Header{"Authorization": {"Token " + testKey}}},
+		{name: "subprotocol", header: http.Header{"Sec-Websocket-Protocol": {"bearer, " + testKey}}, want: true},
+		{name: "subprotocol in separate headers", header: http.Header{"Sec-Websocket-Protocol": {"bearer", testKey}}, want: true},
+		{name: "wrong subprotocol token", header: http.Header{"Sec-Websocket-Protocol": {"bearer, wrong"}}},
+		{name: "bearer marker alone", header: http.Header{"Sec-Websocket-Protocol": {"bearer"}}},
+		{
+			name:   "invalid query token wins over a valid header",
+			query:  "token=wrong",
+			header: http.Header{"Authorization": {"Bearer " + testKey}},
+		},
+		{
+			name:   "invalid header wins over a valid subprotocol",
+			header: http.Header{"Authorization": {"Bearer wrong"}, "Sec-Websocket-Protocol": {"bearer, " + testKey}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/status?"+tt.query, nil)
+			for name, values := range tt.header {
+				r.Header[name] = values
+			}
+			if got := a.Authenticate(r); got != tt.want {
+				t.Errorf("Authenticate = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func basic(user, password string) http.Header {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.SetBasicAuth(user, password)
+	return r.Header
+}
+
+func TestAuthenticateWithoutSecrets(t *testing.T) {
+	// no API key and no user: nothing, not even empty values, is accepted
+	a := New("", "", "")
+
+	for _, r := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/?token=", nil),
+		httptest.NewRequest(http.MethodGet, "/", nil),
+	} {
+		r.SetBasicAuth("", "")
+		if a.Authenticate(r) {
+			t.Errorf("Authenticate(%s %v) succeeded without secrets", r.URL, r.Header)
+		}
+	}
+	if a.validToken("") {
+		t.Error("empty token matched an empty API key")
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	a := New(testKey, "", "")
+	handler := a.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/status", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want 401", rec.Code)
+	}
+	if got := rec.Header().Get("WWW-Authenticate"); got != `Bearer realm="bridge-serial"` {
+		t.Errorf("WWW-Authenticate = %q", got)
+	}
+	if got := rec.Body.String(); got != "{\"error\":\"unauthorized\"}\n" {
+		t.Errorf("body = %q", got)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/status?token="+testKey, nil))
+	if rec.Code != http.StatusTeapot {
+		t.Fatalf("authenticated request got status %d", rec.Code)
+	}
+}
+
+func TestLoadOrCreateAPIKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config", "api_key")
+
+	key, err := LoadOrCreateAPIKey(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(key) != 64 {
+		t.Fatalf("generated key %q is not 32 hex encoded bytes", key)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("key file mode = %v, want 0600", perm)
+	}
+
+	again, err := LoadOrCreateAPIKey(path)
+	if err != nil || again != key {
+		t.Fatalf("second load = %q, %v, want the stored key", again, err)
+	}
+
+	if err := os.WriteFile(path, []byte(" \n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadOrCreateAPIKey(path); err == nil {
+		t.Fatal("empty key file was accepted")
+	}
+}
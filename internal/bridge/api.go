@@ -0,0 +1,182 @@
+package bridge
+
+import (
+	"bridge-serial/config"
+	"bridge-serial/pkg/logger"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// handleLatestWeight serves GET /api/weight/latest
+func (bm *BridgeManager) handleLatestWeight(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	snapshot := bm.snapshot()
+	if snapshot["latest"] == nil {
+		writeError(w, http.StatusNotFound, "no reading received yet")
+		return
+	}
+	writeJSON(w, http.StatusOK, snapshot)
+}
+
+// handleCACert serves GET /cert, the CA certificate operators install to trust wss://localhost
+func (bm *BridgeManager) handleCACert(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if bm.tlsFiles == nil || bm.tlsFiles.CACert == "" {
+		writeError(w, http.StatusNotFound, "no CA certificate configured")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-x509-ca-cert")
+	w.Header().Set("Content-Disposition", `attachment; filename="bridge-serial-ca.pem"`)
+	http.ServeFile(w, r, bm.tlsFiles.CACert)
+}
+
+// writeJSON writes v as a JSON response with the given status code
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Error("failed to write JSON response: %v", err)
+	}
+}
+
+// writeError writes a JSON error response
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+// handleStatus serves GET /api/status
+func (bm *BridgeManager) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	writeJSON(w, http.StatusOK, bm.statusReport())
+}
+
+// handleSerialConnect serves POST /api/serial/connect
+func (bm *BridgeManager) handleSerialConnect(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	err := bm.ConnectSerial()
+	if errors.Is(err, ErrSerialRunning) {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, bm.statusReport())
+}
+
+// handleSerialDisconnect serves POST /api/serial/disconnect
+func (bm *BridgeManager) handleSerialDisconnect(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	if err := bm.DisconnectSerial(); err != nil {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, bm.statusReport())
+}
+
+// handlePorts serves GET /api/ports, the ports the serial transport can open
+func (bm *BridgeManager) handlePorts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	ports, err := bm.serial.ListPorts()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	current := ""
+	if bm.serial.IsConnected() {
+		current = bm.serial.GetPortName()
+	}
+	list := make([]map[string]interface{}, 0, len(ports))
+	for _, port := range ports {
+		list = append(list, map[string]interface{}{
+			"name":          port.Name,
+			"is_usb":        port.IsUSB,
+			"vid":           port.VID,
+			"pid":           port.PID,
+			"serial_number": port.SerialNumber,
+			"product":       port.Product,
+			"connected":     port.Name == current,
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"ports": list})
+}
+
+// configProblems lists the config.FieldErrors wrapped in err
+func configProblems(err error) []string {
+	if fieldErr, ok := err.(*config.FieldError); ok {
+		return []string{fieldErr.Error()}
+	}
+	var problems []string
+	switch e := err.(type) {
+	case interface{ Unwrap() []error }:
+		for _, inner := range e.Unwrap() {
+			problems = append(problems, configProblems(inner)...)
+		}
+	case interface{ Unwrap() error }:
+		problems = configProblems(e.Unwrap())
+	}
+	return problems
+}
+
+// handleSerialConfig serves GET and PUT /api/config/serial. A PUT body holds
+// the settings to change; omitted fields keep their current value.
+func (bm *BridgeManager) handleSerialConfig(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, bm.SerialConfig())
+	case http.MethodPut:
+		cfg := bm.SerialConfig()
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&cfg); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid serial config: "+err.Error())
+			return
+		}
+
+		err := bm.ReconfigureSerial(cfg)
+		if errors.Is(err, ErrInvalidConfig) {
+			problems := configProblems(err)
+			if len(problems) == 0 {
+				problems = []string{err.Error()}
+			}
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+				"error":    ErrInvalidConfig.Error(),
+				"problems": problems,
+			})
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusServiceUnavailable, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, bm.SerialConfig())
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
@@ -0,0 +1,148 @@
+package bridge
+
+import (
+	"bridge-serial/config"
+	"bridge-serial/internal/serial"
+	"bridge-serial/internal/socket"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// freePort returns a localhost address nothing listens on
+func freePort(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return l.Addr().String()
+}
+
+// startPTYBridge starts a bridge without authentication reading a
+// pseudo-terminal and returns it with the terminal and the HTTP address
+func startPTYBridge(t *testing.T) (*BridgeManager, *serial.PTYOpener, string) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+
+	pty, err := serial.NewPTYOpener()
+	if err != nil {
+		t.Skipf("no pseudo-terminals: %v", err)
+	}
+	t.Cleanup(func() { pty.Close() })
+	// the slave side echoes nothing in raw mode, but drain it in case
+	go io.Copy(io.Discard, pty.Master())
+
+	loaded, err := config.Load(config.LoadOptions{Mode: "dev"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := loaded.Config
+	addr := freePort(t)
+	cfg.SocketConfig.Port = addr
+	cfg.Auth.Enabled = false
+	cfg.SerialBridge.Devices = []config.DeviceMatchRule{{PortName: pty.SlaveName()}}
+
+	bm := NewBridgeManager(cfg, pty)
+	if err := bm.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { bm.Stop() })
+	return bm, pty, addr
+}
+
+func TestScaleDataOverPTY(t *testing.T) {
+	_, pty, addr := startPTYBridge(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	conn, err := socket.Dial(ctx, "ws://"+addr+"/ws", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// the port opens in the background, repeat the frame until it is read
+	ticker := time.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case msg, ok := <-conn.Messages():
+			if !ok {
+				t.Fatal("connection closed before scale_data arrived")
+			}
+			if msg.Type != "scale_data" {
+				continue
+			}
+			data, err := json.Marshal(msg.Payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var payload struct {
+				ScaleData struct {
+					Value float64 `json:"value"`
+					Unit  string  `json:"unit"`
+					Type  string  `json:"type"`
+				} `json:"scale_data"`
+			}
+			if err := json.Unmarshal(data, &payload); err != nil {
+				t.Fatalf("decode %s: %v", data, err)
+			}
+			got := payload.ScaleData
+			if got.Value != 12.11 || got.Unit != "g" || got.Type != "WTST" {
+				t.Fatalf("scale_data = %s, want 12.11 g WTST", data)
+			}
+			return
+		case <-ticker.C:
+			if _, err := pty.Master().Write([]byte("WTST   12.11   g\r\n")); err != nil {
+				t.Fatal(err)
+			}
+		case <-ctx.Done():
+			t.Fatal("no scale_data broadcast received")
+		}
+	}
+}
+
+func TestStartWaitsForMissingScale(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	loaded, err := config.Load(config.LoadOptions{Mode: "dev"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := loaded.Config
+	addr := freePort(t)
+	cfg.SocketConfig.Port = addr
+	cfg.Auth.Enabled = false
+	cfg.SerialBridge.Devices = []config.DeviceMatchRule{{PortName: "pipe"}}
+	cfg.SerialBridge.ReconnectInterval = 50 * time.Millisecond
+
+	pipe := serial.NewPipeOpener("pipe")
+	pipe.SetPresent(false)
+	bm := NewBridgeManager(cfg, pipe)
+	if err := bm.Start(); err != nil {
+		t.Fatalf("Start without the scale: %v", err)
+	}
+	defer bm.Stop()
+
+	if !live(addr) {
+		t.Fatal("HTTP server is not serving while the scale is missing")
+	}
+	if bm.serial.IsConnected() {
+		t.Fatal("serial port connected before the scale was plugged in")
+	}
+
+	pipe.SetPresent(true)
+	deadline := time.Now().Add(5 * time.Second)
+	for !bm.serial.IsConnected() {
+		if time.Now().After(deadline) {
+			t.Fatal("run loop did not pick up the scale once it was plugged in")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
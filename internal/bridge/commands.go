@@ -0,0 +1,45 @@
+package bridge
+
+import (
+	"bridge-serial/internal/protocol"
+	"bridge-serial/internal/socket"
+	"fmt"
+)
+
+// registerCommandHandlers routes scale control messages from WebSocket clients to the serial port
+func (bm *BridgeManager) registerCommandHandlers() {
+	bm.wsServer.Handle("tare", bm.commandHandler(protocol.CommandTare))
+	bm.wsServer.Handle("zero", bm.commandHandler(protocol.CommandZero))
+	bm.wsServer.Handle("print", bm.commandHandler(protocol.CommandPrint))
+	bm.wsServer.Handle("raw_write", bm.handleRawWrite)
+}
+
+// commandHandler returns a handler that sends a protocol command to the scale
+func (bm *BridgeManager) commandHandler(cmd protocol.Command) socket.HandlerFunc {
+	return func(client *socket.Client, payload interface{}) (interface{}, error) {
+		if err := bm.SendCommand(cmd); err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"command": cmd}, nil
+	}
+}
+
+// handleRawWrite writes payload.data to the serial port unchanged
+func (bm *BridgeManager) handleRawWrite(client *socket.Client, payload interface{}) (interface{}, error) {
+	fields, ok := payload.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("raw_write payload must be an object")
+	}
+	data, ok := fields["data"].(string)
+	if !ok || data == "" {
+		return nil, fmt.Errorf("raw_write payload requires a non-empty data string")
+	}
+
+	if !bm.IsRunning() {
+		return nil, fmt.Errorf("bridge is not running")
+	}
+	if err := bm.serial.Write([]byte(data)); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"bytes_written": len(data)}, nil
+}
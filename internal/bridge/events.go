@@ -0,0 +1,19 @@
+package bridge
+
+import (
+	"bridge-serial/internal/socket"
+	"bridge-serial/pkg/logger"
+	"encoding/json"
+)
+
+// mirrorEvent forwards a message published to WebSocket clients to the
+// GET /events stream, using the message type as the event name and the
+// WebSocket envelope as its data
+func (bm *BridgeManager) mirrorEvent(message socket.Message) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		logger.Error("failed to marshal event %s: %v", message.Type, err)
+		return
+	}
+	bm.events.Publish(message.Type, data)
+}
@@ -0,0 +1,179 @@
+package bridge
+
+import (
+	"bridge-serial/config"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// parseWindow is how many recent frames the parse error rate covers
+const parseWindow = 100
+
+// healthStats tracks what the readiness check needs to spot a silent or
+// garbled scale
+type healthStats struct {
+	mu          sync.Mutex
+	limits      config.HealthConfig
+	startedAt   time.Time
+	connectedAt time.Time
+	lastReading time.Time
+	parsed      int64
+	failed      int64
+
+	// recent is a ring of the outcomes of the last parseWindow frames, true for a failure
+	recent       [parseWindow]bool
+	recentLen    int
+	recentNext   int
+	recentFailed int
+}
+
+// reset starts a new uptime period
+func (h *healthStats) reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.startedAt = time.Now()
+	h.connectedAt = time.Time{}
+	h.lastReading = time.Time{}
+	h.parsed, h.failed = 0, 0
+	h.recentLen, h.recentNext, h.recentFailed = 0, 0, 0
+}
+
+// setLimits replaces the thresholds the readiness check applies
+func (h *healthStats) setLimits(limits config.HealthConfig) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.limits = limits
+}
+
+// serialConnected restarts the silence timer when the port is opened
+func (h *healthStats) serialConnected() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.connectedAt = time.Now()
+}
+
+// recordFrame counts a frame that parsed into a reading or failed to parse
+func (h *healthStats) recordFrame(ok bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if ok {
+		h.parsed++
+		h.lastReading = time.Now()
+	} else {
+		h.failed++
+	}
+
+	if h.recentLen == parseWindow && h.recent[h.recentNext] {
+		h.recentFailed--
+	}
+	h.recent[h.recentNext] = !ok
+	if !ok {
+		h.recentFailed++
+	}
+	h.recentNext = (h.recentNext + 1) % parseWindow
+	if h.recentLen < parseWindow {
+		h.recentLen++
+	}
+}
+
+// healthReport is the body of GET /health/live and /health/ready
+type healthReport struct {
+	Status                string   `json:"status"`
+	Problems              []string `json:"problems,omitempty"`
+	Running               bool     `json:"running"`
+	SerialConnected       bool     `json:"serial_connected"`
+	Port                  string   `json:"port"`
+	LastReadingAgeSeconds *float64 `json:"last_reading_age_seconds"`
+	ParseErrorRate        float64  `json:"parse_error_rate"`
+	FramesParsed          int64    `json:"frames_parsed"`
+	FramesFailed          int64    `json:"frames_failed"`
+	ForwarderBacklog      int      `json:"forwarder_backlog"`
+	ConnectedClients      int      `json:"connected_clients"`
+	UptimeSeconds         float64  `json:"uptime_seconds"`
+}
+
+// healthReport gathers the current health and lists why the bridge is not ready
+func (bm *BridgeManager) healthReport() healthReport {
+	bm.serialMu.Lock()
+	running := bm.serialEnabled
+	bm.serialMu.Unlock()
+
+	bm.stateMu.RLock()
+	status := bm.status
+	bm.stateMu.RUnlock()
+
+	now := time.Now()
+
+	h := &bm.health
+	h.mu.Lock()
+	limits := h.limits
+	report := healthReport{
+		Running:         running,
+		SerialConnected: status.Connected,
+		Port:            status.Port,
+		FramesParsed:    h.parsed,
+		FramesFailed:    h.failed,
+	}
+	if !h.startedAt.IsZero() {
+		report.UptimeSeconds = now.Sub(h.startedAt).Seconds()
+	}
+	if !h.lastReading.IsZero() {
+		age := now.Sub(h.lastReading).Seconds()
+		report.LastReadingAgeSeconds = &age
+	}
+	if h.recentLen > 0 {
+		report.ParseErrorRate = float64(h.recentFailed) / float64(h.recentLen)
+	}
+	silentSince := h.lastReading
+	if h.connectedAt.After(silentSince) {
+		silentSince = h.connectedAt
+	}
+	h.mu.Unlock()
+
+	if fw := bm.activeForwarder(); fw != nil {
+		report.ForwarderBacklog = fw.Pending()
+	}
+	report.ConnectedClients = bm.wsServer.GetConnectedClientsCount()
+
+	switch {
+	case !running:
+		report.Problems = append(report.Problems, "bridge is not running")
+	case !status.Connected:
+		report.Problems = append(report.Problems, "serial port is not connected")
+	case limits.MaxReadingAge > 0 && now.Sub(silentSince) > limits.MaxReadingAge:
+		report.Problems = append(report.Problems, fmt.Sprintf("no reading for more than %s", limits.MaxReadingAge))
+	}
+	if limits.MaxParseErrorRate > 0 && report.ParseErrorRate > limits.MaxParseErrorRate {
+		report.Problems = append(report.Problems, fmt.Sprintf("parse error rate %.2f above %.2f", report.ParseErrorRate, limits.MaxParseErrorRate))
+	}
+	if limits.MaxForwarderBacklog > 0 && report.ForwarderBacklog > limits.MaxForwarderBacklog {
+		report.Problems = append(report.Problems, fmt.Sprintf("forwarder backlog %d above %d", report.ForwarderBacklog, limits.MaxForwarderBacklog))
+	}
+	return report
+}
+
+// handleHealthLive serves GET /health/live, which succeeds as long as the
+// process answers HTTP
+func (bm *BridgeManager) handleHealthLive(w http.ResponseWriter, r *http.Request) {
+	report := bm.healthReport()
+	report.Status = "ok"
+	report.Problems = nil
+	writeJSON(w, http.StatusOK, report)
+}
+
+// handleHealthReady serves GET /health/ready, answering 503 while readings
+// are not flowing from the scale to clients
+func (bm *BridgeManager) handleHealthReady(w http.ResponseWriter, r *http.Request) {
+	report := bm.healthReport()
+	if len(report.Problems) > 0 {
+		report.Status = "not_ready"
+		writeJSON(w, http.StatusServiceUnavailable, report)
+		return
+	}
+	report.Status = "ready"
+	writeJSON(w, http.StatusOK, report)
+}
@@ -0,0 +1,104 @@
+package bridge
+
+import (
+	"bridge-serial/pkg/logger"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// portListener holds the HTTP port open across servers. A single accept loop
+// hands connections to the listener of the current server, so a config
+// reload can replace the server without releasing and rebinding the port.
+type portListener struct {
+	net.Listener
+	conns     chan net.Conn
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// listenPort binds addr and starts accepting connections on it
+func listenPort(addr string) (*portListener, error) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	p := &portListener{
+		Listener: l,
+		conns:    make(chan net.Conn),
+		done:     make(chan struct{}),
+	}
+	go p.acceptLoop()
+	return p, nil
+}
+
+func (p *portListener) acceptLoop() {
+	for {
+		conn, err := p.Listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			logger.Error("failed to accept HTTP connection: %v", err)
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+
+		// waits for the next server while one is being replaced
+		select {
+		case p.conns <- conn:
+		case <-p.done:
+			conn.Close()
+			return
+		}
+	}
+}
+
+// Close releases the port
+func (p *portListener) Close() error {
+	var err error
+	p.closeOnce.Do(func() {
+		close(p.done)
+		err = p.Listener.Close()
+	})
+	return err
+}
+
+// serverListener returns the listener one server accepts from. Closing it,
+// as http.Server.Shutdown does, leaves the port open for the next server.
+func (p *portListener) serverListener() net.Listener {
+	return &serverListener{port: p, closed: make(chan struct{})}
+}
+
+type serverListener struct {
+	port      *portListener
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func (l *serverListener) Accept() (net.Conn, error) {
+	select {
+	case <-l.closed:
+		return nil, net.ErrClosed
+	default:
+	}
+
+	select {
+	case conn := <-l.port.conns:
+		return conn, nil
+	case <-l.closed:
+		return nil, net.ErrClosed
+	case <-l.port.done:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *serverListener) Close() error {
+	l.closeOnce.Do(func() { close(l.closed) })
+	return nil
+}
+
+func (l *serverListener) Addr() net.Addr {
+	return l.port.Addr()
+}
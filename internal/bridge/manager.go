@@ -2,53 +2,172 @@ package bridge
 
 import (
 	"bridge-serial/config"
+	"bridge-serial/internal/auth"
+	"bridge-serial/internal/cors"
+	"bridge-serial/internal/forwarder"
 	"bridge-serial/internal/model"
+	"bridge-serial/internal/mqtt"
+	"bridge-serial/internal/protocol"
 	"bridge-serial/internal/serial"
 	"bridge-serial/internal/socket"
+	"bridge-serial/internal/sse"
+	"bridge-serial/internal/tlscert"
 	"bridge-serial/pkg/logger"
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
-	"strconv"
+	"reflect"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// modePoll is the SerialBridgeConfig.Mode that requests each weight with a command
+const modePoll = "poll"
+
+// topicStatus is the WebSocket topic for serial connection events
+const topicStatus = "status"
+
+// Errors returned by ConnectSerial, DisconnectSerial and ReconfigureSerial
+var (
+	ErrSerialRunning = errors.New("serial port is already connected")
+	ErrSerialStopped = errors.New("serial port is not connected")
+	ErrInvalidConfig = errors.New("invalid configuration")
+)
+
+// presenceCheckInterval is how often the run loop checks that the serial device is still plugged in
+const presenceCheckInterval = 2 * time.Second
+
 type BridgeManager struct {
 	config     *config.Config
 	serial     *serial.SerialBridge
+	parsing    atomic.Pointer[parseSettings]
+	auth       *auth.Authenticator
+	tlsFiles   *tlscert.Files
+	cors       *cors.Policy
 	wsServer   *socket.Server
+	events     *sse.Broker
 	httpServer *http.Server
-	stopChan   chan bool
+	listener   *portListener
 	isRunning  bool
 	wg         sync.WaitGroup
 	mu         sync.Mutex
+
+	// serialMu guards the serial reader, which ConnectSerial and
+	// DisconnectSerial restart independently of the servers
+	serialMu      sync.Mutex
+	serialEnabled bool
+	serialStop    chan bool
+	serialWg      sync.WaitGroup
+
+	// outputMu guards the outputs a config reload replaces while readings flow
+	outputMu  sync.RWMutex
+	forwarder *forwarder.Forwarder
+	mqtt      *mqtt.Client
+
+	health  healthStats
+	metrics *bridgeMetrics
+
+	// stateMu guards the cached state pushed to clients in snapshots
+	stateMu sync.RWMutex
+	latest  map[string]interface{}
+	status  serialStatus
 }
 
-func NewBridgeManager(config *config.Config) *BridgeManager {
-	return &BridgeManager{
+func NewBridgeManager(config *config.Config, opener serial.PortOpener) *BridgeManager {
+	bm := &BridgeManager{
 		config:     config,
-		serial:     serial.NewSerialBridge(&config.SerialBridge),
+		serial:     serial.NewSerialBridge(&config.SerialBridge, opener),
 		wsServer:   socket.NewServer(),
 		httpServer: nil,
-		stopChan:   make(chan bool),
 	}
+	bm.registerCommandHandlers()
+	bm.wsServer.Handle("get_latest", bm.handleGetLatest)
+	bm.wsServer.OnConnect(bm.sendSnapshot)
+	bm.events = sse.NewBroker(config.SocketConfig.EventHistory)
+	bm.wsServer.OnPublish(bm.mirrorEvent)
+	bm.metrics = bm.newMetrics()
+	return bm
 }
 
 func (bm *BridgeManager) createHTTPServer() *http.Server {
 	mux := http.NewServeMux()
-	mux.HandleFunc("/ws", bm.wsServer.ServeWS)
+	mux.Handle("/ws", bm.protect(http.HandlerFunc(bm.wsServer.ServeWS)))
+	mux.Handle("/events", bm.protect(bm.events))
+	mux.Handle("/api/weight/latest", bm.protect(http.HandlerFunc(bm.handleLatestWeight)))
+	mux.Handle("/api/status", bm.protect(http.HandlerFunc(bm.handleStatus)))
+	mux.Handle("/api/serial/connect", bm.protect(http.HandlerFunc(bm.handleSerialConnect)))
+	mux.Handle("/api/serial/disconnect", bm.protect(http.HandlerFunc(bm.handleSerialDisconnect)))
+	mux.Handle("/api/ports", bm.protect(http.HandlerFunc(bm.handlePorts)))
+	mux.Handle("/api/config/serial", bm.protect(http.HandlerFunc(bm.handleSerialConfig)))
+	mux.HandleFunc("/cert", bm.handleCACert)
+	mux.Handle("/metrics", bm.metrics.registry)
+	mux.HandleFunc("/health/live", bm.handleHealthLive)
+	mux.HandleFunc("/health/ready", bm.handleHealthReady)
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		clientCount := bm.wsServer.GetConnectedClientsCount()
-		fmt.Fprintf(w, `{"status":"ok","connected_clients":%d}`, clientCount)
+		pending := 0
+		if fw := bm.activeForwarder(); fw != nil {
+			pending = fw.Pending()
+		}
+		fmt.Fprintf(w, `{"status":"ok","connected_clients":%d,"forwarder_pending":%d}`, clientCount, pending)
 	})
 
 	return &http.Server{
 		Addr:    bm.config.SocketConfig.Port,
-		Handler: mux,
+		Handler: bm.cors.Middleware(mux),
+	}
+}
+
+// loadTLSFiles returns the operator supplied certificate files, or the
+// generated localhost certificate when none are configured
+func loadTLSFiles(cfg *config.Config) (*tlscert.Files, error) {
+	tlsConfig := cfg.TLS
+	if tlsConfig.CertFile != "" || tlsConfig.KeyFile != "" {
+		if tlsConfig.CertFile == "" || tlsConfig.KeyFile == "" {
+			return nil, fmt.Errorf("both TLS cert and key files must be set")
+		}
+		files := &tlscert.Files{
+			Cert: cfg.ResolvePath(tlsConfig.CertFile),
+			Key:  cfg.ResolvePath(tlsConfig.KeyFile),
+		}
+		if tlsConfig.CAFile != "" {
+			files.CACert = cfg.ResolvePath(tlsConfig.CAFile)
+		}
+		return files, nil
+	}
+
+	return tlscert.EnsureLocalhost(cfg.ResolvePath(tlsConfig.CertDir))
+}
+
+// newForwarder creates the forwarder over a disk queue in the config
+// directory when HTTPClient.Durable is set, or over an in-memory queue
+func (bm *BridgeManager) newForwarder() (*forwarder.Forwarder, error) {
+	clientConfig := &bm.config.HTTPClient
+	if !clientConfig.Durable {
+		return forwarder.New(clientConfig, forwarder.NewMemoryQueue(clientConfig.QueueSize)), nil
+	}
+
+	queue, err := forwarder.OpenDiskQueue(bm.config.ResolvePath(clientConfig.QueueDir), forwarder.DiskQueueOptions{
+		MaxItems: clientConfig.QueueSize,
+		MaxBytes: clientConfig.MaxQueueBytes,
+		MaxAge:   clientConfig.MaxQueueAge,
+	})
+	if err != nil {
+		return nil, err
 	}
+	return forwarder.New(clientConfig, queue), nil
+}
+
+// protect requires authentication for a handler when auth is enabled
+func (bm *BridgeManager) protect(handler http.Handler) http.Handler {
+	if bm.auth == nil {
+		return handler
+	}
+	return bm.auth.Middleware(handler)
 }
 
 func (bm *BridgeManager) Start() error {
@@ -60,46 +179,145 @@ func (bm *BridgeManager) Start() error {
 		return fmt.Errorf("bridge is already running")
 	}
 
-	bm.stopChan = make(chan bool)
+	bm.health.reset()
+	bm.health.setLimits(bm.config.Health)
 
-	bm.httpServer = bm.createHTTPServer()
+	setup, err := prepareHTTP(bm.config)
+	if err != nil {
+		return err
+	}
+	listener, err := listenPort(bm.config.SocketConfig.Port)
+	if err != nil {
+		logger.Error("failed to listen on %s: %v", bm.config.SocketConfig.Port, err)
+		return err
+	}
 
-	bm.wsServer.Start()
-	logger.Info("WebSocket server started on %s", bm.config.SocketConfig.Port)
+	var fw *forwarder.Forwarder
+	if bm.config.HTTPClient.Enabled {
+		fw, err = bm.newForwarder()
+		if err != nil {
+			logger.Error("failed to open forwarder queue: %v", err)
+			listener.Close()
+			return err
+		}
+		fw.Start()
+	}
 
-	bm.wg.Add(1)
-	go func() {
-		defer bm.wg.Done()
-		logger.Info("WebSocket endpoint: ws://localhost%s/ws", bm.config.SocketConfig.Port)
-		logger.Info("Health check: http://localhost%s/health", bm.config.SocketConfig.Port)
+	var mqttClient *mqtt.Client
+	if bm.config.MQTT.Enabled {
+		mqttClient = bm.newMQTTClient()
+		mqttClient.Start()
+	}
 
-		if err := bm.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			logger.Error("HTTP server error: %v", err)
+	bm.outputMu.Lock()
+	bm.forwarder = fw
+	bm.mqtt = mqttClient
+	bm.outputMu.Unlock()
+
+	bm.listener = listener
+	bm.installHTTP(setup)
+	bm.wsServer.Start()
+	logger.Info("WebSocket server started on %s", bm.config.SocketConfig.Port)
+	bm.serveHTTP()
+
+	bm.serialMu.Lock()
+	bm.serialEnabled = true
+	err = bm.prepareSerial()
+	if err == nil {
+		// a missing scale is not fatal, the run loop keeps retrying with
+		// backoff until it is plugged in
+		if connErr := bm.serial.Connect(); connErr != nil {
+			logger.Warn("serial port not available yet, waiting for the scale: %v", connErr)
+			bm.setSerialStatus(false, connErr)
+		} else {
+			bm.serialOpened()
 		}
-		logger.Info("HTTP server goroutine stopped")
-	}()
+		bm.startReading()
+	} else {
+		bm.serialEnabled = false
+	}
+	bm.serialMu.Unlock()
 
-	err := bm.serial.Connect()
 	if err != nil {
-		logger.Error("failed to connect to serial port: %v", err)
-		bm.wsServer.Stop()
-		if bm.httpServer != nil {
-			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-			bm.httpServer.Shutdown(ctx)
-			cancel()
-			bm.httpServer = nil
-		}
-		return fmt.Errorf("failed to connect to serial port: %v", err)
+		bm.stopServices()
+		return fmt.Errorf("failed to prepare the serial reader: %w", err)
 	}
 
 	bm.isRunning = true
-	bm.wg.Add(1)
-	go bm.run()
-
 	logger.Info("bridge started successfully")
 	return nil
 }
 
+// httpSetup holds what the HTTP server is built from, prepared before an
+// old server is torn down so a failure leaves it running
+type httpSetup struct {
+	auth     *auth.Authenticator
+	tlsFiles *tlscert.Files
+	cors     *cors.Policy
+}
+
+// prepareHTTP loads the API key and TLS certificates the config asks for
+func prepareHTTP(cfg *config.Config) (*httpSetup, error) {
+	setup := &httpSetup{cors: cors.New(cfg.SocketConfig.AllowedOrigins)}
+
+	if cfg.Auth.Enabled {
+		keyPath := cfg.ResolvePath(cfg.Auth.APIKeyFile)
+		apiKey, err := auth.LoadOrCreateAPIKey(keyPath)
+		if err != nil {
+			logger.Error("failed to load API key: %v", err)
+			return nil, err
+		}
+		setup.auth = auth.New(apiKey, cfg.User, cfg.Password)
+		logger.Info("authentication enabled, API key stored in %s", keyPath)
+	}
+
+	if cfg.TLS.Enabled {
+		files, err := loadTLSFiles(cfg)
+		if err != nil {
+			logger.Error("failed to prepare TLS certificates: %v", err)
+			return nil, err
+		}
+		setup.tlsFiles = files
+	}
+	return setup, nil
+}
+
+// installHTTP switches to a prepared setup and creates the HTTP server for it
+func (bm *BridgeManager) installHTTP(setup *httpSetup) {
+	bm.auth = setup.auth
+	bm.tlsFiles = setup.tlsFiles
+	bm.cors = setup.cors
+	bm.wsServer.SetOriginChecker(bm.cors.CheckOrigin)
+	bm.httpServer = bm.createHTTPServer()
+}
+
+// serveHTTP serves the HTTP server on the bound port until it is shut down
+func (bm *BridgeManager) serveHTTP() {
+	server := bm.httpServer
+	listener := bm.listener.serverListener()
+	tlsFiles := bm.tlsFiles
+	port := bm.config.SocketConfig.Port
+
+	bm.wg.Add(1)
+	go func() {
+		defer bm.wg.Done()
+		var err error
+		if tlsFiles != nil {
+			logger.Info("WebSocket endpoint: wss://localhost%s/ws", port)
+			logger.Info("Health check: https://localhost%s/health", port)
+			err = server.ServeTLS(listener, tlsFiles.Cert, tlsFiles.Key)
+		} else {
+			logger.Info("WebSocket endpoint: ws://localhost%s/ws", port)
+			logger.Info("Health check: http://localhost%s/health", port)
+			err = server.Serve(listener)
+		}
+		if err != nil && err != http.ErrServerClosed {
+			logger.Error("HTTP server error: %v", err)
+		}
+		logger.Info("HTTP server goroutine stopped")
+	}()
+}
+
 func (bm *BridgeManager) Stop() error {
 	bm.mu.Lock()
 	defer bm.mu.Unlock()
@@ -112,9 +330,35 @@ func (bm *BridgeManager) Stop() error {
 	logger.Info("Stopping bridge...")
 	bm.isRunning = false
 
-	if bm.stopChan != nil {
-		close(bm.stopChan)
-		bm.stopChan = nil
+	bm.serialMu.Lock()
+	bm.serialEnabled = false
+	if bm.serialStop != nil {
+		bm.disconnectSerial()
+	}
+	bm.serialMu.Unlock()
+
+	bm.stopServices()
+
+	logger.Info("bridge stopped")
+	return nil
+}
+
+// stopServices shuts down everything Start launched besides the serial reader
+func (bm *BridgeManager) stopServices() {
+	bm.wsServer.Stop()
+	bm.events.Close()
+
+	if bm.httpServer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := bm.httpServer.Shutdown(ctx); err != nil {
+			logger.Error("HTTP server forced to shutdown: %v", err)
+		}
+		bm.httpServer = nil // Clear reference
+	}
+	if bm.listener != nil {
+		bm.listener.Close()
+		bm.listener = nil
 	}
 
 	done := make(chan struct{})
@@ -130,23 +374,211 @@ func (bm *BridgeManager) Stop() error {
 		logger.Error("Timeout waiting for goroutines to stop")
 	}
 
-	bm.wsServer.Stop()
+	bm.outputMu.Lock()
+	defer bm.outputMu.Unlock()
+	if bm.forwarder != nil {
+		bm.stopForwarder()
+	}
+	if bm.mqtt != nil {
+		bm.stopMQTT()
+		bm.mqtt = nil
+	}
+}
 
-	if bm.httpServer != nil {
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		if err := bm.httpServer.Shutdown(ctx); err != nil {
-			logger.Error("HTTP server forced to shutdown: %v", err)
+// stopForwarder stops and clears the forwarder, adding its counts to the
+// metric totals. The caller must hold outputMu.
+func (bm *BridgeManager) stopForwarder() {
+	bm.forwarder.Stop()
+	bm.metrics.retiredDelivered += bm.forwarder.Delivered()
+	bm.metrics.retiredDropped += bm.forwarder.Dropped()
+	bm.forwarder = nil
+}
+
+// activeForwarder returns the forwarder, or nil when forwarding is disabled
+func (bm *BridgeManager) activeForwarder() *forwarder.Forwarder {
+	bm.outputMu.RLock()
+	defer bm.outputMu.RUnlock()
+	return bm.forwarder
+}
+
+// ConnectSerial opens the serial port and starts reading it without
+// touching the HTTP and WebSocket servers
+func (bm *BridgeManager) ConnectSerial() error {
+	bm.serialMu.Lock()
+	defer bm.serialMu.Unlock()
+	return bm.connectSerial()
+}
+
+// DisconnectSerial stops reading and closes the serial port while the
+// HTTP and WebSocket servers keep running
+func (bm *BridgeManager) DisconnectSerial() error {
+	bm.serialMu.Lock()
+	defer bm.serialMu.Unlock()
+	return bm.disconnectSerial()
+}
+
+// SerialConfig returns a copy of the current serial settings
+func (bm *BridgeManager) SerialConfig() config.SerialBridgeConfig {
+	bm.serialMu.Lock()
+	defer bm.serialMu.Unlock()
+	return bm.config.SerialBridge
+}
+
+// ReconfigureSerial validates and applies new serial settings. A connected
+// port is closed and reopened with them, unless only the parser settings
+// changed, which are swapped without interrupting the port. The servers keep
+// running either way.
+func (bm *BridgeManager) ReconfigureSerial(cfg config.SerialBridgeConfig) error {
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+	}
+
+	bm.serialMu.Lock()
+	defer bm.serialMu.Unlock()
+
+	current := bm.config.SerialBridge
+	if bm.serialStop != nil && onlyParsingChanged(current, cfg) {
+		return bm.swapParser(cfg)
+	}
+	var (
+		opener serial.PortOpener
+		err    error
+	)
+	if cfg.Transport != current.Transport || cfg.TCPAddress != current.TCPAddress {
+		opener, err = serial.NewPortOpener(&cfg)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidConfig, err)
 		}
-		bm.httpServer = nil // Clear reference
 	}
 
-	err := bm.serial.Disconnect()
+	wasConnected := bm.serialStop != nil
+	if wasConnected {
+		bm.disconnectSerial()
+	}
+
+	bm.config.SerialBridge = cfg
+	if opener != nil {
+		bm.serial.SetOpener(opener)
+	}
+	logger.Info("serial settings updated: protocol %s, mode %s, %d baud", cfg.Protocol, cfg.Mode, cfg.BaudRate)
+
+	if wasConnected {
+		if err := bm.connectSerial(); err != nil {
+			return fmt.Errorf("settings applied but the serial port failed to reopen: %w", err)
+		}
+	}
+	return nil
+}
+
+// onlyParsingChanged reports whether next differs from current only in
+// settings the run loop reads through parseSettings
+func onlyParsingChanged(current, next config.SerialBridgeConfig) bool {
+	current.Protocol = next.Protocol
+	current.Mode = next.Mode
+	current.PollCommand = next.PollCommand
+	current.PollInterval = next.PollInterval
+	current.ResponseTimeout = next.ResponseTimeout
+	return reflect.DeepEqual(current, next)
+}
+
+// swapParser replaces the parser and poll settings of the running reader.
+// The caller must hold serialMu.
+func (bm *BridgeManager) swapParser(cfg config.SerialBridgeConfig) error {
+	settings, err := newParseSettings(&cfg)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+
+	// only the fields the serial package does not read, it uses the rest unlocked
+	serialConfig := &bm.config.SerialBridge
+	serialConfig.Protocol = cfg.Protocol
+	serialConfig.Mode = cfg.Mode
+	serialConfig.PollCommand = cfg.PollCommand
+	serialConfig.PollInterval = cfg.PollInterval
+	serialConfig.ResponseTimeout = cfg.ResponseTimeout
+	bm.parsing.Store(settings)
+
+	logger.Info("parser switched to %s in %s mode without reopening the port", cfg.Protocol, cfg.Mode)
+	return nil
+}
+
+// connectSerial builds the parser for the current settings, opens the port
+// and starts the run loop. The caller must hold serialMu.
+func (bm *BridgeManager) connectSerial() error {
+	if bm.serialStop != nil {
+		return ErrSerialRunning
+	}
+	if err := bm.prepareSerial(); err != nil {
+		return err
+	}
+	if err := bm.serial.Connect(); err != nil {
+		return err
+	}
+	bm.serialOpened()
+	bm.startReading()
+	return nil
+}
+
+// prepareSerial builds the parser for the current settings. The caller must
+// hold serialMu.
+func (bm *BridgeManager) prepareSerial() error {
+	if !bm.serialEnabled {
+		return fmt.Errorf("bridge is not running")
+	}
+
+	settings, err := newParseSettings(&bm.config.SerialBridge)
 	if err != nil {
+		logger.Error("failed to prepare the %s parser: %v", bm.config.SerialBridge.Protocol, err)
+		return err
+	}
+	bm.parsing.Store(settings)
+	return nil
+}
+
+// serialOpened records and announces a newly opened port
+func (bm *BridgeManager) serialOpened() {
+	bm.setSerialStatus(true, nil)
+	bm.wsServer.PublishTopic(topicStatus, "serial_connected", map[string]interface{}{
+		"port":      bm.serial.GetPortName(),
+		"timestamp": time.Now().Unix(),
+	})
+}
+
+// startReading starts the run loop, which reopens the port if it is closed.
+// The caller must hold serialMu.
+func (bm *BridgeManager) startReading() {
+	stop := make(chan bool)
+	bm.serialStop = stop
+	bm.serialWg.Add(1)
+	go bm.run(stop)
+}
+
+// disconnectSerial stops the run loop and closes the port. Closing the port
+// first interrupts a blocked read. The caller must hold serialMu.
+func (bm *BridgeManager) disconnectSerial() error {
+	if bm.serialStop == nil {
+		return ErrSerialStopped
+	}
+
+	close(bm.serialStop)
+	bm.serialStop = nil
+	portName := bm.serial.GetPortName()
+	if err := bm.serial.Disconnect(); err != nil {
 		logger.Error("error disconnecting from serial port: %v", err)
 	}
+	bm.serialWg.Wait()
 
-	logger.Info("bridge stopped")
+	// the run loop may have reopened the port before it saw the stop signal
+	if bm.serial.IsConnected() {
+		if err := bm.serial.Disconnect(); err != nil {
+			logger.Error("error disconnecting from serial port: %v", err)
+		}
+	}
+	bm.setSerialStatus(false, nil)
+	bm.wsServer.PublishTopic(topicStatus, "serial_disconnected", map[string]interface{}{
+		"port":      portName,
+		"timestamp": time.Now().Unix(),
+	})
 	return nil
 }
 
@@ -156,31 +588,91 @@ func (bm *BridgeManager) IsRunning() bool {
 	return bm.isRunning
 }
 
-func (bm *BridgeManager) run() {
-	defer bm.wg.Done()
+// run reads the serial port until stop is closed. The channel is passed in
+// because disconnectSerial clears bm.serialStop after closing it.
+func (bm *BridgeManager) run(stop <-chan bool) {
+	defer bm.serialWg.Done()
 
 	ticker := time.NewTicker(100 * time.Millisecond)
 	defer ticker.Stop()
 
+	lastPresenceCheck := time.Now()
+	var retryDelay time.Duration
+	var nextRetry time.Time
+	var nextPoll time.Time
+
 	for {
 		select {
-		case <-bm.stopChan:
+		case <-stop:
 			logger.Info("Stop signal received, exiting run loop")
 			return
 
 		case <-ticker.C:
 			if !bm.serial.IsConnected() {
-				logger.Debug("Serial port not connected, skipping read")
+				if time.Now().Before(nextRetry) {
+					continue
+				}
+				bm.metrics.reconnects.Inc()
+				if err := bm.reconnectSerial(); err != nil {
+					retryDelay = bm.nextRetryDelay(retryDelay)
+					nextRetry = time.Now().Add(retryDelay)
+					logger.Error("failed to reconnect serial port, retrying in %s: %v", retryDelay, err)
+					continue
+				}
+				retryDelay = 0
+				lastPresenceCheck = time.Now()
 				continue
 			}
 
-			data, err := bm.serial.ReadData()
-			if err != nil {
+			if time.Since(lastPresenceCheck) >= presenceCheckInterval {
+				lastPresenceCheck = time.Now()
+				present, err := bm.serial.IsDevicePresent()
+				if err != nil {
+					logger.Error("failed to check serial device presence: %v", err)
+				} else if !present {
+					bm.handlePortLost(fmt.Errorf("device %s is no longer present", bm.serial.GetPortName()))
+					continue
+				}
+			}
+
+			// loaded once per frame, a reload may swap it in between
+			settings := bm.parsing.Load()
+
+			var data string
+			var err error
+			if settings.pollCmd != nil {
+				if time.Now().Before(nextPoll) {
+					continue
+				}
+				nextPoll = time.Now().Add(settings.pollInterval)
+				data, err = bm.serial.SendCommand(settings.pollCmd, settings.responseTimeout, settings.matchReply)
+			} else {
+				data, err = bm.serial.ReadData()
+			}
+			if errors.Is(err, serial.ErrReadTimeout) {
 				logger.Debug("no data from serial port: %v", err)
 				continue
 			}
+			if err != nil {
+				select {
+				case <-stop:
+					// the port was closed by disconnectSerial
+					return
+				default:
+				}
+				bm.handlePortLost(err)
+				continue
+			}
 
-			processedData, err := bm.processScaleData(data)
+			processedData, err := bm.processScaleData(settings, data)
+			if errors.Is(err, protocol.ErrDeviceError) {
+				logger.Warn("scale reported an error: %v", err)
+				continue
+			}
+			if errors.Is(err, protocol.ErrNotWeight) {
+				logger.Info("received non-weight frame: %s", data)
+				continue
+			}
 			if err != nil {
 				logger.Error("error processing scale data: %v", err)
 				continue
@@ -198,46 +690,185 @@ func (bm *BridgeManager) run() {
 	}
 }
 
-func (bm *BridgeManager) sendDataViaSocket(scaleData *model.ScaleDataRequest, rawData string) error {
+// parseSettings is what the run loop needs to turn frames into readings. It
+// is replaced as a whole, so a reload swaps the parser between two frames.
+type parseSettings struct {
+	protocolName    string
+	parser          protocol.Parser
+	pollCmd         []byte
+	matchReply      func(reply string) bool // nil accepts any line
+	pollInterval    time.Duration
+	responseTimeout time.Duration
+}
+
+// newParseSettings builds the parser and poll command for serial settings
+func newParseSettings(cfg *config.SerialBridgeConfig) (*parseSettings, error) {
+	parser, err := protocol.New(cfg.Protocol)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := &parseSettings{
+		protocolName:    cfg.Protocol,
+		parser:          parser,
+		pollInterval:    cfg.PollInterval,
+		responseTimeout: cfg.ResponseTimeout,
+	}
+	if cfg.Mode == modePoll {
+		settings.pollCmd, err = resolvePollCommand(cfg, parser)
+		if err != nil {
+			return nil, err
+		}
+		if matcher, ok := parser.(protocol.ReplyMatcher); ok {
+			pollCmd := settings.pollCmd
+			settings.matchReply = func(reply string) bool {
+				return matcher.MatchesReply(pollCmd, reply)
+			}
+		}
+	}
+	return settings, nil
+}
+
+// resolvePollCommand returns the configured poll command, falling back to the protocol's weigh command
+func resolvePollCommand(cfg *config.SerialBridgeConfig, parser protocol.Parser) ([]byte, error) {
+	if cfg.PollCommand != "" {
+		return []byte(cfg.PollCommand), nil
+	}
+
+	commander, ok := parser.(protocol.Commander)
+	if !ok {
+		return nil, fmt.Errorf("protocol %s has no weigh command, set a poll command", cfg.Protocol)
+	}
+	return commander.Encode(protocol.CommandWeigh)
+}
+
+// scaleTopic returns the WebSocket topic for readings from a port, e.g. "scale/ttyUSB0"
+func scaleTopic(port string) string {
+	return "scale/" + portSlug(port)
+}
+
+// portSlug flattens a port name for use in topics, so "/dev/ttyUSB0" becomes "ttyUSB0"
+func portSlug(port string) string {
+	name := strings.TrimPrefix(port, "/dev/")
+	return strings.ReplaceAll(name, "/", "_")
+}
+
+// handlePortLost closes the serial port and notifies clients so the run loop starts reconnecting
+func (bm *BridgeManager) handlePortLost(cause error) {
+	portName := bm.serial.GetPortName()
+	logger.Error("serial port %s lost: %v", portName, cause)
+
+	if err := bm.serial.Disconnect(); err != nil {
+		logger.Error("error closing lost serial port: %v", err)
+	}
+	bm.setSerialStatus(false, cause)
+
+	bm.wsServer.PublishTopic(topicStatus, "serial_disconnected", map[string]interface{}{
+		"port":      portName,
+		"error":     cause.Error(),
+		"timestamp": time.Now().Unix(),
+	})
+}
+
+// reconnectSerial re-runs device discovery and reopens the serial port
+func (bm *BridgeManager) reconnectSerial() error {
+	if err := bm.serial.Connect(); err != nil {
+		return err
+	}
+
+	logger.Info("serial port %s reconnected", bm.serial.GetPortName())
+	bm.serialOpened()
+	return nil
+}
+
+// nextRetryDelay doubles the previous reconnect delay within the configured bounds
+func (bm *BridgeManager) nextRetryDelay(previous time.Duration) time.Duration {
+	initial := bm.config.SerialBridge.ReconnectInterval
+	if initial <= 0 {
+		initial = time.Second
+	}
+	maxDelay := bm.config.SerialBridge.MaxReconnectInterval
+	if maxDelay < initial {
+		maxDelay = initial
+	}
+
+	if previous <= 0 {
+		return initial
+	}
+	next := previous * 2
+	if next > maxDelay {
+		next = maxDelay
+	}
+	return next
+}
+
+// SendCommand encodes a scale command with the active protocol and writes it to the serial port
+func (bm *BridgeManager) SendCommand(cmd protocol.Command) error {
+	bm.serialMu.Lock()
+	running := bm.serialStop != nil
+	settings := bm.parsing.Load()
+	bm.serialMu.Unlock()
+
+	if !running {
+		return ErrSerialStopped
+	}
+
+	commander, ok := settings.parser.(protocol.Commander)
+	if !ok {
+		return fmt.Errorf("%w: protocol %s does not accept commands", protocol.ErrUnsupportedCommand, settings.protocolName)
+	}
+
+	data, err := commander.Encode(cmd)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("sending %s command to serial port", cmd)
+	return bm.serial.Write(data)
+}
+
+func (bm *BridgeManager) sendDataViaSocket(reading *model.Reading, rawData string) error {
 	payload := map[string]interface{}{
-		"scale_data": scaleData,
+		"scale_data": reading.ScaleData(),
+		"reading":    reading,
 		"raw_data":   rawData,
 		"timestamp":  time.Now().Unix(),
 		"port":       bm.serial.GetPortName(),
 	}
 
-	bm.wsServer.BroadcastMessage("scale_data", payload)
+	bm.setLatest(payload)
+	bm.metrics.readings.Inc()
+	bm.outputMu.RLock()
+	if bm.forwarder != nil {
+		bm.forwarder.Enqueue(forwarder.NewRecord(reading, bm.serial.GetPortName(), time.Now()))
+	}
+	if bm.mqtt != nil {
+		bm.publishMQTTReading(bm.serial.GetPortName(), payload)
+	}
+	bm.outputMu.RUnlock()
+	bm.wsServer.PublishTopic(scaleTopic(bm.serial.GetPortName()), "scale_data", payload)
 	logger.Info("Broadcasted scale data to %d connected clients", bm.wsServer.GetConnectedClientsCount())
 
 	return nil
 }
 
-func (b *BridgeManager) processScaleData(rawData string) (*model.ScaleDataRequest, error) {
+func (bm *BridgeManager) processScaleData(settings *parseSettings, rawData string) (*model.Reading, error) {
 	logger.Info("processing scale data: %s", rawData)
-	var request model.ScaleDataRequest
-	// Parse the raw data format: "WTST   12.11   g" or "WTUS    0.84   g"
-	// Format: [PREFIX][SPACES][VALUE][SPACES][UNIT]
-	fields := strings.Fields(rawData)
-	if len(fields) < 2 {
-		logger.Error("invalid scale data format: %s", rawData)
-		return nil, fmt.Errorf("invalid scale data format: %s", rawData)
+	reading, err := settings.parser.Parse(rawData)
+	if errors.Is(err, protocol.ErrDeviceError) {
+		bm.metrics.deviceErrors.Inc()
+		return nil, err
 	}
-
-	valueStr := fields[len(fields)-2]
-	value, err := strconv.ParseFloat(valueStr, 64)
-	if err != nil {
-		logger.Error("failed to parse value '%s' from scale data: %v", valueStr, err)
-		return nil, fmt.Errorf("failed to parse value '%s' from scale data: %v", valueStr, err)
+	if errors.Is(err, protocol.ErrNotWeight) {
+		return nil, err
 	}
-
-	unit := fields[len(fields)-1]
-	dataType := fields[0]
-
-	request = model.ScaleDataRequest{
-		Value: value,
-		Unit:  unit,
-		Type:  dataType,
+	if err != nil {
+		bm.health.recordFrame(false)
+		bm.metrics.parseFailures.Inc()
+		logger.Error("failed to parse scale data with %s parser: %v", settings.protocolName, err)
+		return nil, err
 	}
-
-	return &request, nil
+	bm.health.recordFrame(true)
+	bm.metrics.parsed.Inc()
+	return reading, nil
 }
@@ -0,0 +1,90 @@
+package bridge
+
+import (
+	"bridge-serial/internal/metrics"
+)
+
+// bridgeMetrics are the counters updated by the bridge itself; counts kept
+// by the serial port, socket server and forwarder are read on each scrape
+type bridgeMetrics struct {
+	registry      *metrics.Registry
+	parsed        *metrics.Counter
+	parseFailures *metrics.Counter
+	deviceErrors  *metrics.Counter
+	readings      *metrics.Counter
+	reconnects    *metrics.Counter
+
+	// counts of forwarders stopped by a restart or reload, so the forwarder
+	// counters keep growing across them; guarded by BridgeManager.outputMu
+	retiredDelivered int64
+	retiredDropped   int64
+}
+
+// newMetrics registers the metrics served at GET /metrics
+func (bm *BridgeManager) newMetrics() *bridgeMetrics {
+	r := metrics.NewRegistry()
+	m := &bridgeMetrics{registry: r}
+
+	r.CounterFunc("bridge_serial_bytes_read_total", "Bytes read from the serial port.", func() float64 {
+		return float64(bm.serial.BytesRead())
+	})
+	r.CounterFunc("bridge_serial_lines_read_total", "Lines read from the serial port.", func() float64 {
+		return float64(bm.serial.LinesRead())
+	})
+	r.GaugeFunc("bridge_serial_connected", "Whether the serial port is open.", func() float64 {
+		return boolValue(bm.serial.IsConnected())
+	})
+	m.reconnects = r.NewCounter("bridge_serial_reconnect_attempts_total", "Attempts to reopen a lost serial port.")
+	m.parsed = r.NewCounter("bridge_parse_success_total", "Frames parsed into a reading.")
+	m.parseFailures = r.NewCounter("bridge_parse_failure_total", "Frames the protocol parser rejected.")
+	m.deviceErrors = r.NewCounter("bridge_device_errors_total", "Error replies received from the scale.")
+	m.readings = r.NewCounter("bridge_readings_broadcast_total", "Readings published to WebSocket, SSE, MQTT and forwarder clients.")
+
+	r.GaugeFunc("bridge_websocket_clients", "Connected WebSocket clients.", func() float64 {
+		return float64(bm.wsServer.GetConnectedClientsCount())
+	})
+	r.GaugeFunc("bridge_sse_clients", "Open Server-Sent Events streams.", func() float64 {
+		return float64(bm.events.Count())
+	})
+	r.CounterFunc("bridge_messages_dropped_total", "WebSocket messages dropped because a channel was full.", func() float64 {
+		return float64(bm.wsServer.DroppedMessages())
+	})
+
+	r.GaugeFunc("bridge_forwarder_queue_depth", "Readings waiting for the HTTP forwarder.", func() float64 {
+		fw := bm.activeForwarder()
+		if fw == nil {
+			return 0
+		}
+		return float64(fw.Pending())
+	})
+	r.CounterFunc("bridge_forwarder_delivered_total", "Readings accepted by the forwarder endpoint.", func() float64 {
+		delivered, _ := bm.forwarderTotals()
+		return float64(delivered)
+	})
+	r.CounterFunc("bridge_forwarder_dropped_total", "Readings the forwarder discarded.", func() float64 {
+		_, dropped := bm.forwarderTotals()
+		return float64(dropped)
+	})
+	return m
+}
+
+// forwarderTotals returns the readings delivered and dropped by every
+// forwarder since the bridge was created
+func (bm *BridgeManager) forwarderTotals() (delivered, dropped int64) {
+	bm.outputMu.RLock()
+	defer bm.outputMu.RUnlock()
+
+	delivered, dropped = bm.metrics.retiredDelivered, bm.metrics.retiredDropped
+	if bm.forwarder != nil {
+		delivered += bm.forwarder.Delivered()
+		dropped += bm.forwarder.Dropped()
+	}
+	return delivered, dropped
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
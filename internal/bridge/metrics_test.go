@@ -0,0 +1,89 @@
+package bridge
+
+import (
+	"bridge-serial/config"
+	"bridge-serial/internal/forwarder"
+	"bridge-serial/internal/model"
+	"bridge-serial/internal/serial"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestForwarderCountersSurviveRestart(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer endpoint.Close()
+
+	loaded, err := config.Load(config.LoadOptions{Mode: "dev"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := loaded.Config
+	cfg.HTTPClient.Enabled = true
+	cfg.HTTPClient.Durable = false
+	cfg.HTTPClient.BaseURL = endpoint.URL
+	bm := NewBridgeManager(cfg, serial.NewPipeOpener("pipe"))
+
+	for round := 1; round <= 2; round++ {
+		fw, err := bm.newForwarder()
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Start()
+		bm.outputMu.Lock()
+		bm.forwarder = fw
+		bm.outputMu.Unlock()
+
+		fw.Enqueue(forwarder.NewRecord(&model.Reading{Value: 1, Unit: "g"}, "pipe", time.Now()))
+		deadline := time.Now().Add(5 * time.Second)
+		for fw.Delivered() == 0 && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		bm.outputMu.Lock()
+		bm.stopForwarder()
+		bm.outputMu.Unlock()
+
+		if delivered, _ := bm.forwarderTotals(); delivered != int64(round) {
+			t.Fatalf("round %d: delivered total = %d, want %d", round, delivered, round)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	bm.metrics.registry.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if !strings.Contains(rec.Body.String(), "\nbridge_forwarder_delivered_total 2\n") {
+		t.Fatalf("metrics do not count both forwarders:\n%s", rec.Body)
+	}
+}
+
+func TestDeviceErrorsAreNotParseFailures(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	loaded, err := config.Load(config.LoadOptions{Mode: "dev"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := loaded.Config
+	cfg.SerialBridge.Protocol = "mt-sics"
+	bm := NewBridgeManager(cfg, serial.NewPipeOpener("pipe"))
+	settings, err := newParseSettings(&cfg.SerialBridge)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, frame := range []string{"ES", "EL", "S I", "S S     12.34 g", "S S"} {
+		bm.processScaleData(settings, frame)
+	}
+
+	if got := bm.metrics.deviceErrors.Value(); got != 3 {
+		t.Errorf("device errors = %d, want 3", got)
+	}
+	if got := bm.metrics.parseFailures.Value(); got != 1 {
+		t.Errorf("parse failures = %d, want 1", got)
+	}
+	if report := bm.healthReport(); report.FramesFailed != 1 || report.ParseErrorRate != 0.5 {
+		t.Errorf("health frames failed = %d at rate %v, want 1 at 0.5", report.FramesFailed, report.ParseErrorRate)
+	}
+}
@@ -0,0 +1,111 @@
+package bridge
+
+import (
+	"bridge-serial/internal/mqtt"
+	"bridge-serial/internal/protocol"
+	"bridge-serial/pkg/logger"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// Retained payloads of MQTTConfig.StatusTopic
+const (
+	mqttOnline  = "online"
+	mqttOffline = "offline"
+)
+
+// mqttCommands are the commands accepted on MQTTConfig.CommandTopic
+var mqttCommands = map[string]protocol.Command{
+	string(protocol.CommandTare):  protocol.CommandTare,
+	string(protocol.CommandZero):  protocol.CommandZero,
+	string(protocol.CommandPrint): protocol.CommandPrint,
+}
+
+// newMQTTClient creates the MQTT output, announcing the bridge on the status
+// topic with the broker publishing the offline status as its will
+func (bm *BridgeManager) newMQTTClient() *mqtt.Client {
+	mqttConfig := bm.config.MQTT
+	statusTopic := bm.mqttTopic(mqttConfig.StatusTopic, "")
+
+	return mqtt.NewClient(mqtt.Options{
+		Broker:    mqttConfig.Broker,
+		ClientID:  mqttConfig.ClientID,
+		Username:  mqttConfig.Username,
+		Password:  mqttConfig.Password,
+		KeepAlive: mqttConfig.KeepAlive,
+		Will: &mqtt.Will{
+			Topic:   statusTopic,
+			Payload: []byte(mqttOffline),
+			Retain:  true,
+		},
+		ReconnectInterval:    time.Second,
+		MaxReconnectInterval: 30 * time.Second,
+		OnConnect: func(c *mqtt.Client) {
+			if err := c.Publish(statusTopic, []byte(mqttOnline), true); err != nil {
+				logger.Error("failed to publish MQTT status: %v", err)
+			}
+			if mqttConfig.CommandTopic != "" {
+				if err := c.Subscribe(bm.mqttTopic(mqttConfig.CommandTopic, "")); err != nil {
+					logger.Error("failed to subscribe to MQTT command topic: %v", err)
+				}
+			}
+		},
+		OnMessage: bm.handleMQTTMessage,
+	})
+}
+
+// stopMQTT marks the bridge offline and disconnects from the broker
+func (bm *BridgeManager) stopMQTT() {
+	statusTopic := bm.mqttTopic(bm.config.MQTT.StatusTopic, "")
+	if err := bm.mqtt.Publish(statusTopic, []byte(mqttOffline), true); err != nil {
+		logger.Debug("failed to publish MQTT offline status: %v", err)
+	}
+	bm.mqtt.Stop()
+}
+
+// publishMQTTReading publishes a reading payload to the topic for its port
+func (bm *BridgeManager) publishMQTTReading(port string, payload map[string]interface{}) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		logger.Error("failed to marshal MQTT reading: %v", err)
+		return
+	}
+
+	topic := bm.mqttTopic(bm.config.MQTT.TopicTemplate, port)
+	if err := bm.mqtt.Publish(topic, data, bm.config.MQTT.Retain); err != nil {
+		logger.Debug("failed to publish reading to MQTT topic %s: %v", topic, err)
+	}
+}
+
+// handleMQTTMessage sends commands received on the command topic to the scale.
+// The payload is either the command name or {"command": "<name>"}.
+func (bm *BridgeManager) handleMQTTMessage(msg mqtt.Message) {
+	name := strings.TrimSpace(string(msg.Payload))
+	var request struct {
+		Command string `json:"command"`
+	}
+	if json.Unmarshal(msg.Payload, &request) == nil && request.Command != "" {
+		name = request.Command
+	}
+
+	cmd, ok := mqttCommands[strings.ToLower(name)]
+	if !ok {
+		logger.Warn("ignoring unknown MQTT command %q on %s", name, msg.Topic)
+		return
+	}
+	// run outside the MQTT read loop, which Stop waits for while holding bm.mu
+	go func() {
+		if err := bm.SendCommand(cmd); err != nil {
+			logger.Error("failed to run MQTT command %s: %v", cmd, err)
+		}
+	}()
+}
+
+// mqttTopic expands the {client_id} and {port} placeholders of a topic template
+func (bm *BridgeManager) mqttTopic(template, port string) string {
+	return strings.NewReplacer(
+		"{client_id}", bm.config.MQTT.ClientID,
+		"{port}", portSlug(port),
+	).Replace(template)
+}
@@ -0,0 +1,24 @@
+package bridge
+
+import (
+	"bridge-serial/config"
+	"testing"
+)
+
+func TestMQTTTopic(t *testing.T) {
+	bm := &BridgeManager{config: &config.Config{MQTT: config.MQTTConfig{ClientID: "bridge-serial"}}}
+
+	tests := []struct {
+		template, port, want string
+	}{
+		{"scales/{port}/weight", "/dev/ttyUSB0", "scales/ttyUSB0/weight"},
+		{"scales/{port}/weight", "/dev/serial/by-id/usb-FTDI", "scales/serial_by-id_usb-FTDI/weight"},
+		{"scales/{client_id}/status", "", "scales/bridge-serial/status"},
+		{"scales/{client_id}/command", "", "scales/bridge-serial/command"},
+	}
+	for _, tt := range tests {
+		if got := bm.mqttTopic(tt.template, tt.port); got != tt.want {
+			t.Errorf("mqttTopic(%q, %q) = %q, want %q", tt.template, tt.port, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,321 @@
+package bridge
+
+import (
+	"bridge-serial/config"
+	"bridge-serial/internal/forwarder"
+	"bridge-serial/pkg/logger"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// WatchConfig applies edits of the config file while the process runs. opts
+// are the options the config was loaded with, so environment variables and
+// flags keep overriding the file after a reload.
+func (bm *BridgeManager) WatchConfig(opts config.LoadOptions) (*config.Watcher, error) {
+	path := opts.File
+	if path == "" {
+		path = bm.config.GetDefaultConfigPath()
+	}
+	return config.Watch(path, func() { bm.reloadConfig(opts, path) })
+}
+
+// reloadConfig loads the config again and applies it, telling clients when
+// the edit was rejected
+func (bm *BridgeManager) reloadConfig(opts config.LoadOptions, path string) {
+	logger.Info("config file %s changed, reloading", path)
+
+	loaded, err := config.Load(opts)
+	if err != nil {
+		logger.Error("rejected config reload: %v", err)
+		bm.publishConfigError(path, err)
+		return
+	}
+	for _, note := range loaded.Notes {
+		logger.Info("%s", note)
+	}
+
+	changed, err := bm.ApplyConfig(loaded.Config)
+	if err != nil {
+		logger.Error("failed to apply config reload: %v", err)
+		bm.publishConfigError(path, err)
+		return
+	}
+	if len(changed) == 0 {
+		logger.Info("config reloaded, nothing changed")
+		return
+	}
+	logger.Info("config reloaded, applied changes to %v", changed)
+	bm.wsServer.PublishTopic(topicStatus, "config_reloaded", map[string]interface{}{
+		"file":      path,
+		"changed":   changed,
+		"timestamp": time.Now().Unix(),
+	})
+}
+
+// publishConfigError tells clients that a config edit was rejected and the
+// previous settings are still in effect
+func (bm *BridgeManager) publishConfigError(path string, err error) {
+	problems := configProblems(err)
+	if len(problems) == 0 {
+		problems = []string{err.Error()}
+	}
+	bm.wsServer.PublishTopic(topicStatus, "config_error", map[string]interface{}{
+		"file":      path,
+		"error":     err.Error(),
+		"problems":  problems,
+		"timestamp": time.Now().Unix(),
+	})
+}
+
+// ApplyConfig validates next and applies the sections that differ from the
+// current config, returning their names. Serial line changes reopen the port
+// while parser changes are swapped between frames, socket, auth and TLS
+// changes replace the HTTP server, and forwarder and MQTT changes replace
+// those outputs. The config is applied as a whole: what can fail is prepared
+// before anything is torn down, and a forwarder or serial port that fails to
+// open rolls back the sections applied before it. App settings only take
+// effect after a restart.
+func (bm *BridgeManager) ApplyConfig(next *config.Config) ([]string, error) {
+	if err := next.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+	}
+
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+
+	previous := *bm.config
+	// the REST API edits the serial settings under serialMu alone
+	previous.SerialBridge = bm.SerialConfig()
+	var changed []string
+
+	if previous.App != next.App {
+		logger.Warn("app settings changed, restart to apply them")
+	}
+
+	var rebind *httpRebind
+	httpChanged := !reflect.DeepEqual(previous.SocketConfig, next.SocketConfig) || previous.Auth != next.Auth ||
+		previous.TLS != next.TLS || previous.User != next.User || previous.Password != next.Password
+	if httpChanged && bm.isRunning {
+		var err error
+		rebind, err = bm.prepareRebind(next)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if previous.HTTPClient != next.HTTPClient {
+		if err := bm.applyForwarder(next.HTTPClient); err != nil {
+			rebind.cancel()
+			return nil, err
+		}
+		changed = append(changed, "http_client")
+	}
+
+	if !reflect.DeepEqual(previous.SerialBridge, next.SerialBridge) {
+		bm.serialMu.Lock()
+		wasReading := bm.serialStop != nil
+		bm.serialMu.Unlock()
+
+		if err := bm.ReconfigureSerial(next.SerialBridge); err != nil {
+			logger.Error("failed to apply serial settings, restoring the previous config: %v", err)
+			bm.restoreSerial(previous.SerialBridge, wasReading)
+			if previous.HTTPClient != next.HTTPClient {
+				if err := bm.applyForwarder(previous.HTTPClient); err != nil {
+					logger.Error("failed to restore the previous forwarder: %v", err)
+				}
+			}
+			rebind.cancel()
+			return nil, err
+		}
+		changed = append(changed, "serial_bridge")
+	}
+
+	// nothing below can fail
+	if httpChanged {
+		if rebind != nil {
+			bm.commitRebind(next, rebind)
+		} else {
+			bm.setHTTPConfig(next)
+		}
+		changed = append(changed, "http")
+	}
+
+	if previous.MQTT != next.MQTT {
+		bm.applyMQTT(next.MQTT)
+		changed = append(changed, "mqtt")
+	}
+
+	if previous.Health != next.Health {
+		bm.config.Health = next.Health
+		bm.health.setLimits(next.Health)
+		changed = append(changed, "health")
+	}
+
+	return changed, nil
+}
+
+// restoreSerial puts back the serial settings of a failed edit and reopens
+// the port when it was being read before
+func (bm *BridgeManager) restoreSerial(previous config.SerialBridgeConfig, wasReading bool) {
+	if !reflect.DeepEqual(bm.SerialConfig(), previous) {
+		if err := bm.ReconfigureSerial(previous); err != nil {
+			logger.Error("failed to restore the previous serial settings: %v", err)
+			return
+		}
+	}
+	if wasReading {
+		if err := bm.ConnectSerial(); err != nil && !errors.Is(err, ErrSerialRunning) {
+			logger.Error("failed to reopen the serial port with the previous settings: %v", err)
+		}
+	}
+}
+
+// httpRebind is what a new HTTP server is built from, acquired while the
+// old server keeps running
+type httpRebind struct {
+	setup *httpSetup
+	port  *portListener // nil when the port is unchanged
+}
+
+// cancel releases a prepared rebind that will not be committed
+func (r *httpRebind) cancel() {
+	if r != nil && r.port != nil {
+		r.port.Close()
+	}
+}
+
+// prepareRebind loads the API key and certificates for next and binds its
+// port when that changed, so a port in use rejects the edit
+func (bm *BridgeManager) prepareRebind(next *config.Config) (*httpRebind, error) {
+	setup, err := prepareHTTP(next)
+	if err != nil {
+		return nil, err
+	}
+
+	rebind := &httpRebind{setup: setup}
+	if next.SocketConfig.Port != bm.config.SocketConfig.Port {
+		rebind.port, err = listenPort(next.SocketConfig.Port)
+		if err != nil {
+			return nil, fmt.Errorf("failed to listen on %s: %w", next.SocketConfig.Port, err)
+		}
+	}
+	return rebind, nil
+}
+
+// commitRebind replaces the HTTP server with one for the new socket, auth
+// and TLS settings. On an unchanged port, connections arriving meanwhile
+// wait for the new server instead of being refused.
+func (bm *BridgeManager) commitRebind(next *config.Config, rebind *httpRebind) {
+	if next.SocketConfig.EventHistory != bm.config.SocketConfig.EventHistory {
+		logger.Warn("event history size changed, restart to apply it")
+	}
+
+	logger.Info("rebinding HTTP server from %s to %s", bm.config.SocketConfig.Port, next.SocketConfig.Port)
+	bm.wsServer.CloseClients()
+	bm.events.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := bm.httpServer.Shutdown(ctx); err != nil {
+		logger.Error("HTTP server forced to shutdown: %v", err)
+	}
+
+	if rebind.port != nil {
+		bm.listener.Close()
+		bm.listener = rebind.port
+	}
+	bm.setHTTPConfig(next)
+	bm.installHTTP(rebind.setup)
+	bm.serveHTTP()
+}
+
+func (bm *BridgeManager) setHTTPConfig(next *config.Config) {
+	bm.config.SocketConfig = next.SocketConfig
+	bm.config.Auth = next.Auth
+	bm.config.TLS = next.TLS
+	bm.config.User = next.User
+	bm.config.Password = next.Password
+}
+
+// applyForwarder replaces the forwarder with one for the new settings.
+// Readings wait while the two are swapped, so each goes to exactly one of
+// them; the old forwarder's disk queue is reopened by the new one.
+func (bm *BridgeManager) applyForwarder(next config.HTTPClientConfig) error {
+	bm.outputMu.Lock()
+	defer bm.outputMu.Unlock()
+
+	if !bm.isRunning {
+		bm.config.HTTPClient = next
+		return nil
+	}
+
+	// readings still queued in memory move to the new forwarder
+	previous := bm.config.HTTPClient
+	var carried []forwarder.Record
+	if old := bm.forwarder; old != nil {
+		bm.stopForwarder()
+		carried = old.Remaining()
+	}
+
+	bm.config.HTTPClient = next
+	if !next.Enabled {
+		if len(carried) > 0 {
+			logger.Warn("forwarding disabled, discarding %d pending readings", len(carried))
+			bm.metrics.retiredDropped += int64(len(carried))
+		} else {
+			logger.Info("forwarding disabled")
+		}
+		return nil
+	}
+
+	fw, err := bm.newForwarder()
+	if err != nil {
+		openErr := fmt.Errorf("failed to open forwarder queue: %w", err)
+		logger.Error("%v, keeping the previous forwarder settings", openErr)
+		bm.config.HTTPClient = previous
+		if previous.Enabled {
+			restored, err := bm.newForwarder()
+			if err != nil {
+				logger.Error("failed to restore the previous forwarder: %v", err)
+				bm.metrics.retiredDropped += int64(len(carried))
+				return openErr
+			}
+			enqueueAll(restored, carried)
+			restored.Start()
+			bm.forwarder = restored
+		}
+		return openErr
+	}
+	enqueueAll(fw, carried)
+	fw.Start()
+	bm.forwarder = fw
+	return nil
+}
+
+// enqueueAll queues records taken over from a previous forwarder
+func enqueueAll(fw *forwarder.Forwarder, records []forwarder.Record) {
+	for _, rec := range records {
+		fw.Enqueue(rec)
+	}
+	if len(records) > 0 {
+		logger.Info("moved %d pending readings to the new forwarder", len(records))
+	}
+}
+
+// applyMQTT reconnects to the broker with the new settings
+func (bm *BridgeManager) applyMQTT(next config.MQTTConfig) {
+	bm.outputMu.Lock()
+	defer bm.outputMu.Unlock()
+
+	if bm.mqtt != nil {
+		bm.stopMQTT()
+		bm.mqtt = nil
+	}
+	bm.config.MQTT = next
+	if bm.isRunning && next.Enabled {
+		bm.mqtt = bm.newMQTTClient()
+		bm.mqtt.Start()
+	}
+}
@@ -0,0 +1,148 @@
+package bridge
+
+import (
+	"bridge-serial/config"
+	"bridge-serial/internal/forwarder"
+	"bridge-serial/internal/model"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// live reports whether the bridge answers /health/live on addr
+func live(addr string) bool {
+	client := http.Client{Timeout: 2 * time.Second}
+	resp, err := client.Get("http://" + addr + "/health/live")
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode == http.StatusOK
+}
+
+// nextConfig returns a copy of the running config to edit
+func nextConfig(bm *BridgeManager) *config.Config {
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+	next := *bm.config
+	next.SocketConfig.AllowedOrigins = append([]string(nil), next.SocketConfig.AllowedOrigins...)
+	return &next
+}
+
+func TestApplyConfigSamePortKeepsServing(t *testing.T) {
+	bm, _, addr := startPTYBridge(t)
+
+	next := nextConfig(bm)
+	next.SocketConfig.AllowedOrigins = append(next.SocketConfig.AllowedOrigins, "example.com")
+	changed, err := bm.ApplyConfig(next)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changed) != 1 || changed[0] != "http" {
+		t.Fatalf("changed = %v, want [http]", changed)
+	}
+	if !live(addr) {
+		t.Fatal("bridge stopped answering after the rebind")
+	}
+}
+
+func TestApplyConfigPortInUse(t *testing.T) {
+	bm, _, addr := startPTYBridge(t)
+
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busy.Close()
+
+	next := nextConfig(bm)
+	next.SocketConfig.Port = busy.Addr().String()
+	next.HTTPClient.Enabled = true
+	if _, err := bm.ApplyConfig(next); err == nil {
+		t.Fatal("ApplyConfig succeeded on a port in use")
+	}
+
+	if bm.config.SocketConfig.Port != addr || bm.config.HTTPClient.Enabled || bm.activeForwarder() != nil {
+		t.Fatalf("rejected edit was partly applied: port %s, forwarding %v",
+			bm.config.SocketConfig.Port, bm.config.HTTPClient.Enabled)
+	}
+	if !live(addr) {
+		t.Fatal("bridge stopped answering on its previous port")
+	}
+}
+
+func TestApplyConfigRollsBackWhenSerialFails(t *testing.T) {
+	bm, pty, addr := startPTYBridge(t)
+
+	next := nextConfig(bm)
+	newAddr := freePort(t)
+	next.SocketConfig.Port = newAddr
+	next.SerialBridge.BaudRate = 19200
+	next.SerialBridge.Devices = []config.DeviceMatchRule{{PortName: "/dev/pts/missing"}}
+	if _, err := bm.ApplyConfig(next); err == nil {
+		t.Fatal("ApplyConfig succeeded with a missing serial port")
+	}
+
+	serialConfig := bm.SerialConfig()
+	if bm.config.SocketConfig.Port != addr || serialConfig.BaudRate != 9600 || serialConfig.Devices[0].PortName != pty.SlaveName() {
+		t.Fatalf("failed edit was not rolled back: port %s, %d baud, device %s",
+			bm.config.SocketConfig.Port, serialConfig.BaudRate, serialConfig.Devices[0].PortName)
+	}
+	if !bm.serial.IsConnected() {
+		t.Fatal("serial port was not reopened with the previous settings")
+	}
+	if !live(addr) || live(newAddr) {
+		t.Fatal("HTTP server moved although the edit was rolled back")
+	}
+}
+
+func TestApplyConfigKeepsQueuedReadings(t *testing.T) {
+	bm, _, _ := startPTYBridge(t)
+
+	var accepted atomic.Int64
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// only the new token is accepted, so nothing is delivered before the reload
+		if r.Header.Get("X-Token") != "new" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		accepted.Add(1)
+	}))
+	defer endpoint.Close()
+
+	next := nextConfig(bm)
+	next.HTTPClient.Enabled = true
+	next.HTTPClient.Durable = false
+	next.HTTPClient.BaseURL = endpoint.URL
+	next.HTTPClient.AuthHeader = "X-Token"
+	next.HTTPClient.AuthToken = "old"
+	next.HTTPClient.MaxRetries = 1000
+	next.HTTPClient.RetryBackoff = 10 * time.Millisecond
+	next.HTTPClient.MaxBackoff = 10 * time.Millisecond
+	if _, err := bm.ApplyConfig(next); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		bm.activeForwarder().Enqueue(forwarder.NewRecord(&model.Reading{Value: float64(i), Unit: "g"}, "pty", time.Now()))
+	}
+
+	next = nextConfig(bm)
+	next.HTTPClient.AuthToken = "new"
+	if _, err := bm.ApplyConfig(next); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for accepted.Load() < 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d of 3 queued readings delivered after the reload", accepted.Load())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, dropped := bm.forwarderTotals(); dropped != 0 {
+		t.Fatalf("%d readings dropped by the reload", dropped)
+	}
+}
@@ -0,0 +1,66 @@
+package bridge
+
+import (
+	"bridge-serial/config"
+	"bridge-serial/internal/serial"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// TestUnauthenticatedRoutes pins the routes served without credentials:
+// probes and metrics scrapers cannot present a token, and /cert must be
+// reachable before the browser trusts the bridge
+func TestUnauthenticatedRoutes(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	loaded, err := config.Load(config.LoadOptions{Mode: "dev"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := loaded.Config
+	addr := freePort(t)
+	cfg.SocketConfig.Port = addr
+	if !cfg.Auth.Enabled {
+		t.Fatal("authentication is not enabled by default")
+	}
+
+	bm := NewBridgeManager(cfg, serial.NewPipeOpener("pipe"))
+	if err := bm.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer bm.Stop()
+
+	public := map[string]bool{
+		"/metrics":      true,
+		"/health":       true,
+		"/health/live":  true,
+		"/health/ready": true,
+		"/cert":         true,
+	}
+	routes := []string{
+		"/ws",
+		"/events",
+		"/api/weight/latest",
+		"/api/status",
+		"/api/serial/connect",
+		"/api/serial/disconnect",
+		"/api/ports",
+		"/api/config/serial",
+	}
+	for route := range public {
+		routes = append(routes, route)
+	}
+
+	client := http.Client{Timeout: 5 * time.Second}
+	for _, route := range routes {
+		resp, err := client.Get("http://" + addr + route)
+		if err != nil {
+			t.Fatalf("GET %s: %v", route, err)
+		}
+		resp.Body.Close()
+
+		if unauthorized := resp.StatusCode == http.StatusUnauthorized; unauthorized == public[route] {
+			t.Errorf("GET %s without credentials = %d, public %v", route, resp.StatusCode, public[route])
+		}
+	}
+}
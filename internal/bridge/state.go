@@ -0,0 +1,85 @@
+package bridge
+
+import (
+	"bridge-serial/internal/socket"
+	"time"
+)
+
+// serialStatus is the serial connection state reported to clients
+type serialStatus struct {
+	Connected bool   `json:"connected"`
+	Port      string `json:"port"`
+	Since     int64  `json:"since"`
+	Error     string `json:"error,omitempty"`
+}
+
+// setSerialStatus records the serial connection state for snapshots
+func (bm *BridgeManager) setSerialStatus(connected bool, cause error) {
+	status := serialStatus{
+		Connected: connected,
+		Port:      bm.serial.GetPortName(),
+		Since:     time.Now().Unix(),
+	}
+	if cause != nil {
+		status.Error = cause.Error()
+	}
+
+	bm.stateMu.Lock()
+	bm.status = status
+	bm.stateMu.Unlock()
+
+	if connected {
+		bm.health.serialConnected()
+	}
+}
+
+// setLatest caches the last scale_data payload for snapshots
+func (bm *BridgeManager) setLatest(payload map[string]interface{}) {
+	bm.stateMu.Lock()
+	bm.latest = payload
+	bm.stateMu.Unlock()
+}
+
+// snapshot returns the last scale_data payload, or nil if none was received, and the serial status
+func (bm *BridgeManager) snapshot() map[string]interface{} {
+	bm.stateMu.RLock()
+	defer bm.stateMu.RUnlock()
+
+	var latest interface{}
+	if bm.latest != nil {
+		latest = bm.latest
+	}
+	return map[string]interface{}{
+		"latest": latest,
+		"serial": bm.status,
+	}
+}
+
+// statusReport describes the bridge for GET /api/status
+func (bm *BridgeManager) statusReport() map[string]interface{} {
+	// serialMu rather than bm.mu, which Stop holds while waiting for handlers
+	bm.serialMu.Lock()
+	running := bm.serialEnabled
+	reading := bm.serialStop != nil
+	serialConfig := bm.config.SerialBridge
+	bm.serialMu.Unlock()
+
+	report := bm.snapshot()
+	report["running"] = running
+	report["serial_reading"] = reading
+	report["protocol"] = serialConfig.Protocol
+	report["mode"] = serialConfig.Mode
+	report["transport"] = serialConfig.Transport
+	report["connected_clients"] = bm.wsServer.GetConnectedClientsCount()
+	return report
+}
+
+// sendSnapshot pushes the cached state to a newly connected client
+func (bm *BridgeManager) sendSnapshot(client *socket.Client) {
+	client.SendMessage("snapshot", bm.snapshot())
+}
+
+// handleGetLatest replies to get_latest requests with the cached state
+func (bm *BridgeManager) handleGetLatest(client *socket.Client, payload interface{}) (interface{}, error) {
+	return bm.snapshot(), nil
+}
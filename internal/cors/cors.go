@@ -0,0 +1,106 @@
+package cors
+
+import (
+	"bridge-serial/pkg/logger"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Policy decides which browser origins may call the bridge. Entries are
+// either "*", a full origin such as "https://app.example.com", a host such
+// as "localhost" (any scheme and port) or a wildcard subdomain such as
+// "*.example.com" (subdomains only, not the apex).
+type Policy struct {
+	allowed []string
+}
+
+// New creates a policy from allowlist entries
+func New(allowed []string) *Policy {
+	entries := make([]string, 0, len(allowed))
+	for _, entry := range allowed {
+		if entry = strings.ToLower(strings.TrimSpace(entry)); entry != "" {
+			entries = append(entries, strings.TrimSuffix(entry, "/"))
+		}
+	}
+	return &Policy{allowed: entries}
+}
+
+// AllowOrigin reports whether the Origin header value is on the allowlist
+func (p *Policy) AllowOrigin(origin string) bool {
+	u, err := url.Parse(strings.ToLower(origin))
+	if err != nil || u.Host == "" {
+		return false
+	}
+	host := u.Hostname()
+	full := u.Scheme + "://" + u.Host
+
+	for _, entry := range p.allowed {
+		switch {
+		case entry == "*":
+			return true
+		case strings.Contains(entry, "://"):
+			if entry == full {
+				return true
+			}
+		case strings.HasPrefix(entry, "*."):
+			if strings.HasSuffix(host, entry[1:]) {
+				return true
+			}
+		case entry == host:
+			return true
+		}
+	}
+	return false
+}
+
+// CheckOrigin is a websocket.Upgrader CheckOrigin function. Requests without
+// an Origin header come from non-browser clients and are allowed.
+func (p *Policy) CheckOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || p.AllowOrigin(origin) {
+		return true
+	}
+	logger.Warn("rejected WebSocket connection from origin %s", origin)
+	return false
+}
+
+// Middleware adds CORS headers for allowed origins and answers preflight
+// requests, including Chrome's Private Network Access preflight that is sent
+// when a public site calls the bridge on localhost
+func (p *Policy) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Origin")
+		if !p.AllowOrigin(origin) {
+			logger.Warn("rejected %s %s from origin %s", r.Method, r.URL.Path, origin)
+			http.Error(w, "origin not allowed", http.StatusForbidden)
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+
+		if r.Method != http.MethodOptions || r.Header.Get("Access-Control-Request-Method") == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		if headers := r.Header.Get("Access-Control-Request-Headers"); headers != "" {
+			w.Header().Set("Access-Control-Allow-Headers", headers)
+		} else {
+			w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+		}
+		if r.Header.Get("Access-Control-Request-Private-Network") == "true" {
+			w.Header().Set("Access-Control-Allow-Private-Network", "true")
+		}
+		w.Header().Set("Access-Control-Max-Age", "600")
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,172 @@
+package cors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAllowOrigin(t *testing.T) {
+	p := New([]string{"https://app.example.com/", " LOCALHOST ", "*.scales.test"})
+
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"https://app.example.com", true},
+		{"https://APP.example.com", true},
+		{"http://app.example.com", false},
+		{"https://app.example.com:8443", false},
+		{"https://evil.example.com", false},
+		{"http://localhost:3000", true},
+		{"https://localhost", true},
+		{"http://localhost.evil.com", false},
+		{"https://line1.scales.test", true},
+		{"https://a.b.scales.test", true},
+		{"https://scales.test", false},
+		{"https://evilscales.test", false},
+		{"null", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := p.AllowOrigin(tt.origin); got != tt.want {
+			t.Errorf("AllowOrigin(%q) = %v, want %v", tt.origin, got, tt.want)
+		}
+	}
+
+	if !New([]string{"*"}).AllowOrigin("https://anything.example") {
+		t.Error("* did not allow every origin")
+	}
+	if New(nil).AllowOrigin("http://localhost") {
+		t.Error("empty allowlist allowed an origin")
+	}
+}
+
+func TestCheckOrigin(t *testing.T) {
+	p := New([]string{"localhost"})
+
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"", true}, // non-browser clients send no Origin
+		{"http://localhost:5173", true},
+		{"https://evil.example.com", false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/ws", nil)
+		if tt.origin != "" {
+			r.Header.Set("Origin", tt.origin)
+		}
+		if got := p.CheckOrigin(r); got != tt.want {
+			t.Errorf("CheckOrigin(%q) = %v, want %v", tt.origin, got, tt.want)
+		}
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	handler := New([]string{"https://app.example.com"}).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	tests := []struct {
+		name    string
+		method  string
+		header  map[string]string
+		status  int
+		headers map[string]string // expected response headers, "" means absent
+	}{
+		{
+			name:    "no origin",
+			method:  http.MethodGet,
+			status:  http.StatusTeapot,
+			headers: map[string]string{"Access-Control-Allow-Origin": "", "Vary": ""},
+		},
+		{
+			name:   "allowed origin",
+			method: http.MethodGet,
+			header: map[string]string{"Origin": "https://app.example.com"},
+			status: http.StatusTeapot,
+			headers: map[string]string{
+				"Access-Control-Allow-Origin":      "https://app.example.com",
+				"Access-Control-Allow-Credentials": "true",
+				"Vary":                             "Origin",
+			},
+		},
+		{
+			name:    "disallowed origin",
+			method:  http.MethodGet,
+			header:  map[string]string{"Origin": "https://evil.example.com"},
+			status:  http.StatusForbidden,
+			headers: map[string]string{"Access-Control-Allow-Origin": "", "Vary": "Origin"},
+		},
+		{
+			name:    "disallowed preflight",
+			method:  http.MethodOptions,
+			header:  map[string]string{"Origin": "https://evil.example.com", "Access-Control-Request-Method": "POST"},
+			status:  http.StatusForbidden,
+			headers: map[string]string{"Access-Control-Allow-Methods": ""},
+		},
+		{
+			name:   "preflight",
+			method: http.MethodOptions,
+			header: map[string]string{"Origin": "https://app.example.com", "Access-Control-Request-Method": "POST"},
+			status: http.StatusNoContent,
+			headers: map[string]string{
+				"Access-Control-Allow-Origin":          "https://app.example.com",
+				"Access-Control-Allow-Methods":         "GET, POST, PUT, DELETE, OPTIONS",
+				"Access-Control-Allow-Headers":         "Authorization, Content-Type",
+				"Access-Control-Allow-Private-Network": "",
+				"Access-Control-Max-Age":               "600",
+			},
+		},
+		{
+			name:   "preflight with requested headers",
+			method: http.MethodOptions,
+			header: map[string]string{
+				"Origin":                         "https://app.example.com",
+				"Access-Control-Request-Method":  "PUT",
+				"Access-Control-Request-Headers": "x-request-id",
+			},
+			status:  http.StatusNoContent,
+			headers: map[string]string{"Access-Control-Allow-Headers": "x-request-id"},
+		},
+		{
+			name:   "private network preflight",
+			method: http.MethodOptions,
+			header: map[string]string{
+				"Origin":                                 "https://app.example.com",
+				"Access-Control-Request-Method":          "GET",
+				"Access-Control-Request-Private-Network": "true",
+			},
+			status:  http.StatusNoContent,
+			headers: map[string]string{"Access-Control-Allow-Private-Network": "true"},
+		},
+		{
+			name:    "options without a request method is not a preflight",
+			method:  http.MethodOptions,
+			header:  map[string]string{"Origin": "https://app.example.com"},
+			status:  http.StatusTeapot,
+			headers: map[string]string{"Access-Control-Allow-Methods": ""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/api/status", nil)
+			for name, value := range tt.header {
+				r.Header.Set(name, value)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, r)
+
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+			for name, want := range tt.headers {
+				if got := rec.Header().Get(name); got != want {
+					t.Errorf("%s = %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
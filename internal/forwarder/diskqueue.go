@@ -0,0 +1,436 @@
+package forwarder
+
+import (
+	"bridge-serial/pkg/logger"
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// compactBytes is how many acknowledged or evicted bytes accumulate at the
+// head of the log before the pending records are copied to a fresh log file
+const compactBytes = 1 << 20
+
+// DiskQueueOptions bounds a DiskQueue. Zero values disable a limit.
+type DiskQueueOptions struct {
+	MaxItems int
+	MaxBytes int64
+	MaxAge   time.Duration
+}
+
+// DiskQueue is an append-only queue persisted in a directory. Records are
+// appended as JSON lines to queue-<generation>.log and fsynced before Push
+// returns; the position of the oldest unacknowledged record is kept in
+// queue.ack, which is replaced atomically. A record is acknowledged only
+// after delivery, so a crash can cause a redelivery but never a loss.
+type DiskQueue struct {
+	dir     string
+	opts    DiskQueueOptions
+	notify  chan struct{}
+	dropped atomic.Int64
+
+	mu           sync.Mutex
+	log          *os.File
+	generation   int64
+	head         int64 // offset of the oldest pending record
+	size         int64 // offset where the next record is appended
+	entries      []diskEntry
+	pendingBytes int64
+	removed      uint64 // entries removed from the front so far
+	peeked       uint64 // value of removed when the head was last peeked
+}
+
+// diskEntry locates a pending record in the log
+type diskEntry struct {
+	offset   int64
+	length   int64
+	enqueued time.Time
+}
+
+// diskRecord is one line of the log
+type diskRecord struct {
+	EnqueuedAt int64  `json:"enqueued_at"`
+	Record     Record `json:"record"`
+}
+
+// OpenDiskQueue opens or creates the queue in dir and recovers pending records
+func OpenDiskQueue(dir string, opts DiskQueueOptions) (*DiskQueue, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create queue directory: %w", err)
+	}
+
+	q := &DiskQueue{
+		dir:    dir,
+		opts:   opts,
+		notify: make(chan struct{}, 1),
+	}
+
+	generation, head, err := q.readAck()
+	if err != nil {
+		return nil, err
+	}
+	q.generation = generation
+
+	if err := q.openLog(); err != nil {
+		return nil, err
+	}
+	if err := q.recover(head); err != nil {
+		q.log.Close()
+		return nil, err
+	}
+	q.removeStaleLogs()
+
+	q.mu.Lock()
+	evicted := q.enforceLimits(time.Now())
+	q.mu.Unlock()
+	if evicted {
+		if err := q.persistHead(); err != nil {
+			q.log.Close()
+			return nil, err
+		}
+	}
+
+	if len(q.entries) > 0 {
+		logger.Info("recovered %d pending readings from %s", len(q.entries), dir)
+	}
+	return q, nil
+}
+
+func (q *DiskQueue) Push(rec Record) error {
+	now := time.Now()
+	line, err := json.Marshal(diskRecord{EnqueuedAt: now.UnixNano(), Record: rec})
+	if err != nil {
+		return fmt.Errorf("failed to marshal queued reading: %w", err)
+	}
+	line = append(line, '\n')
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, err := q.log.Write(line); err != nil {
+		return fmt.Errorf("failed to append to queue: %w", err)
+	}
+	if err := q.log.Sync(); err != nil {
+		return fmt.Errorf("failed to sync queue: %w", err)
+	}
+
+	q.entries = append(q.entries, diskEntry{offset: q.size, length: int64(len(line)), enqueued: now})
+	q.size += int64(len(line))
+	q.pendingBytes += int64(len(line))
+
+	if q.enforceLimits(now) {
+		q.saveAck()
+	}
+
+	signal(q.notify)
+	return nil
+}
+
+func (q *DiskQueue) Peek(ctx context.Context) (Record, error) {
+	for {
+		q.mu.Lock()
+		if q.enforceLimits(time.Now()) {
+			q.saveAck()
+		}
+
+		if len(q.entries) > 0 {
+			q.peeked = q.removed
+			rec, err := q.readEntry(q.entries[0])
+			q.mu.Unlock()
+			return rec, err
+		}
+		q.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return Record{}, ctx.Err()
+		case <-q.notify:
+		}
+	}
+}
+
+func (q *DiskQueue) Ack() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.entries) == 0 || q.removed != q.peeked {
+		return nil
+	}
+	q.popHead()
+	return q.persistHead()
+}
+
+func (q *DiskQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.entries)
+}
+
+func (q *DiskQueue) Dropped() int64 {
+	return q.dropped.Load()
+}
+
+func (q *DiskQueue) Persistent() bool {
+	return true
+}
+
+func (q *DiskQueue) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.log.Close()
+}
+
+// enforceLimits evicts the oldest records over the size and age limits and
+// reports whether any were evicted. The caller must hold mu.
+func (q *DiskQueue) enforceLimits(now time.Time) bool {
+	evicted := 0
+	for len(q.entries) > 0 {
+		head := q.entries[0]
+		overItems := q.opts.MaxItems > 0 && len(q.entries) > q.opts.MaxItems
+		overBytes := q.opts.MaxBytes > 0 && q.pendingBytes > q.opts.MaxBytes
+		expired := q.opts.MaxAge > 0 && now.Sub(head.enqueued) > q.opts.MaxAge
+		if !overItems && !overBytes && !expired {
+			break
+		}
+		q.popHead()
+		evicted++
+	}
+
+	if evicted > 0 {
+		q.dropped.Add(int64(evicted))
+		logger.Error("forwarder queue limits reached, dropped %d oldest readings", evicted)
+	}
+	return evicted > 0
+}
+
+// popHead removes the oldest entry. The caller must hold mu.
+func (q *DiskQueue) popHead() {
+	head := q.entries[0]
+	q.entries = q.entries[1:]
+	q.pendingBytes -= head.length
+	q.head = head.offset + head.length
+	q.removed++
+}
+
+// readEntry reads and decodes a record from the log. The caller must hold mu.
+func (q *DiskQueue) readEntry(entry diskEntry) (Record, error) {
+	buf := make([]byte, entry.length)
+	if _, err := q.log.ReadAt(buf, entry.offset); err != nil {
+		return Record{}, fmt.Errorf("failed to read queued reading: %w", err)
+	}
+
+	var stored diskRecord
+	if err := json.Unmarshal(buf, &stored); err != nil {
+		return Record{}, fmt.Errorf("failed to decode queued reading: %w", err)
+	}
+	return stored.Record, nil
+}
+
+// recover scans the log from head, rebuilding the pending entries and
+// truncating a partially written last line left by a crash
+func (q *DiskQueue) recover(head int64) error {
+	info, err := q.log.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat queue log: %w", err)
+	}
+	if head > info.Size() {
+		head = info.Size()
+	}
+
+	reader := bufio.NewReader(io.NewSectionReader(q.log, head, info.Size()-head))
+	offset := head
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			if len(line) > 0 {
+				logger.Error("discarding %d bytes of incomplete queued reading", len(line))
+				if err := q.log.Truncate(offset); err != nil {
+					return fmt.Errorf("failed to truncate queue log: %w", err)
+				}
+			}
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read queue log: %w", err)
+		}
+
+		var stored diskRecord
+		if err := json.Unmarshal(line, &stored); err != nil {
+			logger.Error("skipping corrupt queued reading at offset %d: %v", offset, err)
+		} else {
+			q.entries = append(q.entries, diskEntry{
+				offset:   offset,
+				length:   int64(len(line)),
+				enqueued: time.Unix(0, stored.EnqueuedAt),
+			})
+			q.pendingBytes += int64(len(line))
+		}
+		offset += int64(len(line))
+	}
+
+	q.size = offset
+	q.head = head
+	if len(q.entries) > 0 {
+		q.head = q.entries[0].offset
+	}
+	return nil
+}
+
+// compact copies the pending records into the log of the next generation and
+// switches queue.ack to it. Until queue.ack is replaced the old log stays
+// authoritative, so a crash at any point leaves a consistent queue.
+// The caller must hold mu.
+func (q *DiskQueue) compact() error {
+	nextPath := q.logPath(q.generation + 1)
+	next, err := os.OpenFile(nextPath, os.O_CREATE|os.O_TRUNC|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create queue log: %w", err)
+	}
+
+	if _, err := io.Copy(next, io.NewSectionReader(q.log, q.head, q.size-q.head)); err != nil {
+		next.Close()
+		os.Remove(nextPath)
+		return fmt.Errorf("failed to compact queue log: %w", err)
+	}
+	if err := next.Sync(); err != nil {
+		next.Close()
+		os.Remove(nextPath)
+		return fmt.Errorf("failed to sync queue log: %w", err)
+	}
+
+	// the new generation is only used once the ack points at it; until then
+	// the old log and position stay in effect, in memory and on disk
+	if err := q.writeAckAt(q.generation+1, 0); err != nil {
+		next.Close()
+		os.Remove(nextPath)
+		return err
+	}
+
+	old := q.log
+	oldPath := q.logPath(q.generation)
+	shift := q.head
+
+	q.log = next
+	q.generation++
+	q.size -= shift
+	q.head = 0
+	for i := range q.entries {
+		q.entries[i].offset -= shift
+	}
+
+	old.Close()
+	os.Remove(oldPath)
+	return nil
+}
+
+// saveAck persists the head after an eviction. A failure only means the
+// evicted records are seen again on the next start, so it is logged.
+// The caller must hold mu.
+func (q *DiskQueue) saveAck() {
+	if err := q.persistHead(); err != nil {
+		logger.Error("failed to persist queue position: %v", err)
+	}
+}
+
+// persistHead records the head after records were removed, compacting the
+// log once enough bytes were removed ahead of it. Evictions count as well,
+// so the log stays bounded while deliveries fail. The caller must hold mu.
+func (q *DiskQueue) persistHead() error {
+	threshold := int64(compactBytes)
+	if q.opts.MaxBytes > 0 && q.opts.MaxBytes < threshold {
+		// keep the log within about twice a small size limit
+		threshold = q.opts.MaxBytes
+	}
+	if q.head >= threshold {
+		return q.compact()
+	}
+	return q.writeAckAt(q.generation, q.head)
+}
+
+// writeAckAt atomically records a log generation and head offset. The
+// caller must hold mu.
+func (q *DiskQueue) writeAckAt(generation, head int64) error {
+	tmpPath := filepath.Join(q.dir, "queue.ack.tmp")
+	data := fmt.Sprintf("%d %d\n", generation, head)
+
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write queue ack: %w", err)
+	}
+	if _, err := tmp.WriteString(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write queue ack: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync queue ack: %w", err)
+	}
+	tmp.Close()
+
+	if err := os.Rename(tmpPath, filepath.Join(q.dir, "queue.ack")); err != nil {
+		return fmt.Errorf("failed to replace queue ack: %w", err)
+	}
+	return nil
+}
+
+// readAck returns the log generation and head offset, or zeros for a new queue
+func (q *DiskQueue) readAck() (int64, int64, error) {
+	data, err := os.ReadFile(filepath.Join(q.dir, "queue.ack"))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read queue ack: %w", err)
+	}
+
+	fields := strings.Fields(string(data))
+	if len(fields) != 2 {
+		return 0, 0, fmt.Errorf("invalid queue ack file: %q", data)
+	}
+	generation, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid queue generation: %w", err)
+	}
+	head, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid queue offset: %w", err)
+	}
+	return generation, head, nil
+}
+
+func (q *DiskQueue) openLog() error {
+	log, err := os.OpenFile(q.logPath(q.generation), os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open queue log: %w", err)
+	}
+	q.log = log
+	return nil
+}
+
+// removeStaleLogs deletes logs of other generations left by an interrupted compaction
+func (q *DiskQueue) removeStaleLogs() {
+	matches, err := filepath.Glob(filepath.Join(q.dir, "queue-*.log"))
+	if err != nil {
+		return
+	}
+	current := q.logPath(q.generation)
+	for _, path := range matches {
+		if path != current {
+			os.Remove(path)
+		}
+	}
+}
+
+func (q *DiskQueue) logPath(generation int64) string {
+	return filepath.Join(q.dir, fmt.Sprintf("queue-%d.log", generation))
+}
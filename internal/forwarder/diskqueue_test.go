@@ -0,0 +1,126 @@
+package forwarder
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDiskQueueCompactsWhileEvicting(t *testing.T) {
+	dir := t.TempDir()
+	opts := DiskQueueOptions{MaxItems: 10, MaxBytes: 4096}
+	q, err := OpenDiskQueue(dir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// nothing is acknowledged, as while the endpoint is down
+	for i := 0; i < 1000; i++ {
+		if err := q.Push(Record{Port: fmt.Sprintf("p%d", i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if q.Len() != 10 {
+		t.Fatalf("Len = %d, want 10", q.Len())
+	}
+
+	logs, _ := filepath.Glob(filepath.Join(dir, "queue-*.log"))
+	if len(logs) != 1 {
+		t.Fatalf("logs = %v, want one", logs)
+	}
+	info, err := os.Stat(logs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() > 2*opts.MaxBytes {
+		t.Fatalf("log is %d bytes, want at most %d", info.Size(), 2*opts.MaxBytes)
+	}
+	q.Close()
+
+	// the compacted log still holds the newest records in order
+	q, err = OpenDiskQueue(dir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+	if q.Len() != 10 {
+		t.Fatalf("Len after reopen = %d, want 10", q.Len())
+	}
+	for i := 990; i < 1000; i++ {
+		rec, err := q.Peek(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := fmt.Sprintf("p%d", i); rec.Port != want {
+			t.Fatalf("Peek = %s, want %s", rec.Port, want)
+		}
+		if err := q.Ack(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestDiskQueueFailedCompactionKeepsLog(t *testing.T) {
+	dir := t.TempDir()
+	q, err := OpenDiskQueue(dir, DiskQueueOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		if err := q.Push(Record{Port: fmt.Sprintf("p%d", i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := q.Peek(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if err := q.Ack(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the ack file cannot be replaced, so the compacted log is never used
+	blocker := filepath.Join(dir, "queue.ack.tmp")
+	if err := os.Mkdir(blocker, 0755); err != nil {
+		t.Fatal(err)
+	}
+	q.mu.Lock()
+	err = q.compact()
+	q.mu.Unlock()
+	if err == nil {
+		t.Fatal("compaction succeeded without writing the ack")
+	}
+	if logs, _ := filepath.Glob(filepath.Join(dir, "queue-*.log")); len(logs) != 1 {
+		t.Fatalf("logs after failed compaction = %v, want one", logs)
+	}
+	os.Remove(blocker)
+
+	if err := q.Push(Record{Port: "p5"}); err != nil {
+		t.Fatal(err)
+	}
+	q.Close()
+
+	q, err = OpenDiskQueue(dir, DiskQueueOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+	if q.Len() != 4 {
+		t.Fatalf("Len after reopen = %d, want 4", q.Len())
+	}
+	for i := 2; i < 6; i++ {
+		rec, err := q.Peek(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := fmt.Sprintf("p%d", i); rec.Port != want {
+			t.Fatalf("Peek = %s, want %s", rec.Port, want)
+		}
+		if err := q.Ack(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package forwarder
+
+import (
+	"bridge-serial/config"
+	"bridge-serial/internal/model"
+	"bridge-serial/pkg/logger"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Record is the JSON body posted for each reading: the scale_data fields plus metadata
+type Record struct {
+	model.ScaleDataRequest
+	Stable    bool   `json:"stable"`
+	Port      string `json:"port"`
+	RawData   string `json:"raw_data"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// NewRecord builds the record posted for a reading
+func NewRecord(reading *model.Reading, port string, timestamp time.Time) Record {
+	return Record{
+		ScaleDataRequest: *reading.ScaleData(),
+		Stable:           reading.Stable,
+		Port:             port,
+		RawData:          reading.Raw,
+		Timestamp:        timestamp.Unix(),
+	}
+}
+
+// errPermanent marks delivery failures that retrying cannot fix
+var errPermanent = errors.New("permanent delivery failure")
+
+// Forwarder posts readings to HTTPClientConfig.BaseURL + Path in queue
+// order, retrying failed deliveries with exponential backoff. A record is
+// removed from the queue only once it has been delivered or, from an
+// in-memory queue, rejected.
+type Forwarder struct {
+	config *config.HTTPClientConfig
+	url    string
+	client *http.Client
+	queue  Queue
+
+	dropped   atomic.Int64
+	delivered atomic.Int64
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New creates a forwarder for the configured endpoint that delivers from queue
+func New(cfg *config.HTTPClientConfig, queue Queue) *Forwarder {
+	return &Forwarder{
+		config: cfg,
+		url:    strings.TrimRight(cfg.BaseURL, "/") + "/" + strings.TrimLeft(cfg.Path, "/"),
+		client: &http.Client{Timeout: cfg.Timeout},
+		queue:  queue,
+	}
+}
+
+// Start launches the delivery worker
+func (f *Forwarder) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	f.cancel = cancel
+
+	f.wg.Add(1)
+	go f.run(ctx)
+	logger.Info("forwarding readings to %s", f.url)
+}
+
+// Stop stops the delivery worker and closes the queue. Pending records are
+// kept by a persistent queue and discarded otherwise.
+func (f *Forwarder) Stop() {
+	if f.cancel != nil {
+		f.cancel()
+	}
+	f.wg.Wait()
+	logger.Info("forwarder stopped with %d pending readings", f.Pending())
+
+	if err := f.queue.Close(); err != nil {
+		logger.Error("failed to close forwarder queue: %v", err)
+	}
+}
+
+// Remaining returns the records a stopped forwarder left in an in-memory
+// queue, so a replacement forwarder can deliver them. A persistent queue
+// keeps its records on disk and returns none.
+func (f *Forwarder) Remaining() []Record {
+	if queue, ok := f.queue.(*MemoryQueue); ok {
+		return queue.Records()
+	}
+	return nil
+}
+
+// Enqueue queues a record for delivery
+func (f *Forwarder) Enqueue(rec Record) {
+	if err := f.queue.Push(rec); err != nil {
+		f.dropped.Add(1)
+		logger.Error("failed to queue reading: %v", err)
+	}
+}
+
+// Pending returns the number of records waiting for delivery
+func (f *Forwarder) Pending() int {
+	return f.queue.Len()
+}
+
+// Dropped returns the number of records discarded because the queue was full or delivery failed
+func (f *Forwarder) Dropped() int64 {
+	return f.dropped.Load() + f.queue.Dropped()
+}
+
+// Delivered returns the number of records accepted by the endpoint
+func (f *Forwarder) Delivered() int64 {
+	return f.delivered.Load()
+}
+
+// run delivers queued records one at a time until ctx is cancelled
+func (f *Forwarder) run(ctx context.Context) {
+	defer f.wg.Done()
+
+	for {
+		rec, err := f.queue.Peek(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			// an unreadable record would block the queue forever
+			logger.Error("dropping unreadable queued reading: %v", err)
+			f.dropped.Add(1)
+			f.ack()
+			continue
+		}
+
+		if err := f.deliver(ctx, rec); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			f.dropped.Add(1)
+			logger.Error("failed to forward reading: %v", err)
+		} else {
+			f.delivered.Add(1)
+		}
+		f.ack()
+	}
+}
+
+func (f *Forwarder) ack() {
+	if err := f.queue.Ack(); err != nil {
+		logger.Error("failed to acknowledge queued reading: %v", err)
+	}
+}
+
+// deliver posts a record, retrying transient failures with exponential
+// backoff. Records in a persistent queue are retried until the endpoint
+// accepts them, rejections included, otherwise delivery gives up after
+// MaxRetries or on a rejection.
+func (f *Forwarder) deliver(ctx context.Context, rec Record) error {
+	body, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to marshal reading: %w", err)
+	}
+
+	backoff := f.config.RetryBackoff
+	if backoff <= 0 {
+		backoff = time.Second
+	}
+	for attempt := 0; ; attempt++ {
+		err = f.post(ctx, body)
+		if err == nil {
+			return nil
+		}
+		if errors.Is(err, errPermanent) {
+			if !f.queue.Persistent() {
+				return err
+			}
+			// a durable record is only removed once delivered, so wait the
+			// longest delay for the endpoint to be fixed
+			if f.config.MaxBackoff > backoff {
+				backoff = f.config.MaxBackoff
+			}
+		} else if !f.queue.Persistent() && attempt >= f.config.MaxRetries {
+			return fmt.Errorf("giving up after %d attempts: %w", attempt+1, err)
+		}
+
+		logger.Error("failed to forward reading, retrying in %s: %v", backoff, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = nextBackoff(backoff, f.config.MaxBackoff)
+	}
+}
+
+// post sends one request. 4xx responses other than 408 and 429 are permanent failures.
+func (f *Forwarder) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, f.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%w: %v", errPermanent, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if f.config.AuthHeader != "" && f.config.AuthToken != "" {
+		req.Header.Set(f.config.AuthHeader, f.config.AuthToken)
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests:
+		return fmt.Errorf("endpoint returned %s", resp.Status)
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		return fmt.Errorf("%w: endpoint returned %s", errPermanent, resp.Status)
+	default:
+		return fmt.Errorf("endpoint returned %s", resp.Status)
+	}
+}
+
+// nextBackoff doubles the delay up to maxDelay
+func nextBackoff(current, maxDelay time.Duration) time.Duration {
+	if current <= 0 {
+		current = time.Second
+	}
+	next := current * 2
+	if maxDelay > 0 && next > maxDelay {
+		next = maxDelay
+	}
+	return next
+}
@@ -0,0 +1,79 @@
+package forwarder
+
+import (
+	"bridge-serial/config"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// rejectOnce serves 400 to the first request and 200 afterwards
+func rejectOnce(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &requests
+}
+
+func TestRejectedRecords(t *testing.T) {
+	tests := []struct {
+		name      string
+		queue     func(t *testing.T) Queue
+		delivered int64
+		dropped   int64
+	}{
+		{
+			name: "durable queue keeps the record",
+			queue: func(t *testing.T) Queue {
+				q, err := OpenDiskQueue(t.TempDir(), DiskQueueOptions{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				return q
+			},
+			delivered: 1,
+		},
+		{
+			name:    "memory queue drops the record",
+			queue:   func(t *testing.T) Queue { return NewMemoryQueue(10) },
+			dropped: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, requests := rejectOnce(t)
+			cfg := &config.HTTPClientConfig{
+				BaseURL:      srv.URL,
+				Path:         "/readings",
+				Timeout:      time.Second,
+				MaxRetries:   3,
+				RetryBackoff: 10 * time.Millisecond,
+				MaxBackoff:   20 * time.Millisecond,
+			}
+			f := New(cfg, tt.queue(t))
+			f.Enqueue(Record{Port: "COM1"})
+			f.Start()
+			defer f.Stop()
+
+			deadline := time.Now().Add(5 * time.Second)
+			for f.Delivered()+f.Dropped() == 0 || f.Pending() != 0 {
+				if time.Now().After(deadline) {
+					t.Fatalf("record neither delivered nor dropped after %d requests", requests.Load())
+				}
+				time.Sleep(5 * time.Millisecond)
+			}
+			if f.Delivered() != tt.delivered || f.Dropped() != tt.dropped {
+				t.Fatalf("delivered %d dropped %d, want %d and %d", f.Delivered(), f.Dropped(), tt.delivered, tt.dropped)
+			}
+		})
+	}
+}
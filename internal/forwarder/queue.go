@@ -0,0 +1,127 @@
+package forwarder
+
+import (
+	"bridge-serial/pkg/logger"
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// Queue holds records waiting for delivery. It has a single consumer that
+// calls Peek for the oldest record and Ack once it has been delivered.
+type Queue interface {
+	// Push appends a record, evicting the oldest records when the queue is full
+	Push(rec Record) error
+	// Peek blocks until a record is available or ctx is done
+	Peek(ctx context.Context) (Record, error)
+	// Ack removes the record returned by the last Peek, unless the queue
+	// limits evicted it in the meantime
+	Ack() error
+	// Len returns the number of pending records
+	Len() int
+	// Dropped returns the number of records evicted by the queue limits
+	Dropped() int64
+	// Persistent reports whether pending records survive a restart
+	Persistent() bool
+	Close() error
+}
+
+// MemoryQueue is a bounded in-memory queue that evicts the oldest record when full
+type MemoryQueue struct {
+	maxItems int
+	notify   chan struct{}
+	dropped  atomic.Int64
+
+	mu      sync.Mutex
+	records []Record
+	removed uint64 // records removed from the front so far
+	peeked  uint64 // value of removed when the head was last peeked
+}
+
+// NewMemoryQueue creates a queue holding at most maxItems records
+func NewMemoryQueue(maxItems int) *MemoryQueue {
+	if maxItems <= 0 {
+		maxItems = 1
+	}
+	return &MemoryQueue{
+		maxItems: maxItems,
+		notify:   make(chan struct{}, 1),
+	}
+}
+
+func (q *MemoryQueue) Push(rec Record) error {
+	q.mu.Lock()
+	if len(q.records) >= q.maxItems {
+		q.records = q.records[1:]
+		q.removed++
+		q.dropped.Add(1)
+		logger.Error("forwarder queue full, dropped oldest reading")
+	}
+	q.records = append(q.records, rec)
+	q.mu.Unlock()
+
+	signal(q.notify)
+	return nil
+}
+
+func (q *MemoryQueue) Peek(ctx context.Context) (Record, error) {
+	for {
+		q.mu.Lock()
+		if len(q.records) > 0 {
+			rec := q.records[0]
+			q.peeked = q.removed
+			q.mu.Unlock()
+			return rec, nil
+		}
+		q.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return Record{}, ctx.Err()
+		case <-q.notify:
+		}
+	}
+}
+
+func (q *MemoryQueue) Ack() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.records) > 0 && q.removed == q.peeked {
+		q.records = q.records[1:]
+		q.removed++
+	}
+	return nil
+}
+
+func (q *MemoryQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.records)
+}
+
+func (q *MemoryQueue) Dropped() int64 {
+	return q.dropped.Load()
+}
+
+// Records returns a copy of the pending records, oldest first
+func (q *MemoryQueue) Records() []Record {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]Record(nil), q.records...)
+}
+
+func (q *MemoryQueue) Persistent() bool {
+	return false
+}
+
+func (q *MemoryQueue) Close() error {
+	return nil
+}
+
+// signal wakes a blocked Peek without blocking the producer
+func signal(notify chan struct{}) {
+	select {
+	case notify <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,37 @@
+package forwarder
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMemoryQueueAckAfterEviction(t *testing.T) {
+	q := NewMemoryQueue(2)
+	q.Push(Record{Port: "A"})
+	q.Push(Record{Port: "B"})
+
+	rec, err := q.Peek(context.Background())
+	if err != nil || rec.Port != "A" {
+		t.Fatalf("Peek = %v, %v, want A", rec.Port, err)
+	}
+
+	// A is evicted while it is being delivered, the Ack must not remove B
+	q.Push(Record{Port: "C"})
+	if err := q.Ack(); err != nil {
+		t.Fatal(err)
+	}
+
+	if q.Len() != 2 {
+		t.Fatalf("Len = %d, want 2", q.Len())
+	}
+	for _, want := range []string{"B", "C"} {
+		rec, err := q.Peek(context.Background())
+		if err != nil || rec.Port != want {
+			t.Fatalf("Peek = %v, %v, want %s", rec.Port, err, want)
+		}
+		q.Ack()
+	}
+	if q.Dropped() != 1 {
+		t.Fatalf("Dropped = %d, want 1", q.Dropped())
+	}
+}
@@ -0,0 +1,140 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// contentType is the Prometheus text exposition format version 0.0.4
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Counter is a monotonically increasing value
+type Counter struct {
+	value atomic.Uint64
+}
+
+// Inc adds one to the counter
+func (c *Counter) Inc() {
+	c.value.Add(1)
+}
+
+// Add adds n to the counter
+func (c *Counter) Add(n uint64) {
+	c.value.Add(n)
+}
+
+// Value returns the current count
+func (c *Counter) Value() uint64 {
+	return c.value.Load()
+}
+
+// Gauge is a value that can go up and down
+type Gauge struct {
+	bits atomic.Uint64
+}
+
+// Set replaces the gauge value
+func (g *Gauge) Set(v float64) {
+	g.bits.Store(math.Float64bits(v))
+}
+
+// Value returns the current value
+func (g *Gauge) Value() float64 {
+	return math.Float64frombits(g.bits.Load())
+}
+
+// metric is one registered metric and how to read its value
+type metric struct {
+	name  string
+	help  string
+	kind  string
+	value func() float64
+}
+
+// Registry holds metrics and serves them in the Prometheus text format
+type Registry struct {
+	mu      sync.Mutex
+	metrics []metric
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// NewCounter registers and returns a counter
+func (r *Registry) NewCounter(name, help string) *Counter {
+	c := &Counter{}
+	r.CounterFunc(name, help, func() float64 { return float64(c.Value()) })
+	return c
+}
+
+// NewGauge registers and returns a gauge
+func (r *Registry) NewGauge(name, help string) *Gauge {
+	g := &Gauge{}
+	r.GaugeFunc(name, help, g.Value)
+	return g
+}
+
+// CounterFunc registers a counter whose value is read from fn on each scrape,
+// for counts kept by another component
+func (r *Registry) CounterFunc(name, help string, fn func() float64) {
+	r.register(metric{name: name, help: help, kind: "counter", value: fn})
+}
+
+// GaugeFunc registers a gauge whose value is read from fn on each scrape
+func (r *Registry) GaugeFunc(name, help string, fn func() float64) {
+	r.register(metric{name: name, help: help, kind: "gauge", value: fn})
+}
+
+func (r *Registry) register(m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.metrics {
+		if existing.name == m.name {
+			panic(fmt.Sprintf("metric %s registered twice", m.name))
+		}
+	}
+	r.metrics = append(r.metrics, m)
+}
+
+// ServeHTTP writes every metric in registration order
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	r.mu.Lock()
+	metrics := append([]metric(nil), r.metrics...)
+	r.mu.Unlock()
+
+	w.Header().Set("Content-Type", contentType)
+	out := bufio.NewWriter(w)
+	for _, m := range metrics {
+		fmt.Fprintf(out, "# HELP %s %s\n", m.name, m.help)
+		fmt.Fprintf(out, "# TYPE %s %s\n", m.name, m.kind)
+		fmt.Fprintf(out, "%s %s\n", m.name, formatValue(m.value()))
+	}
+	out.Flush()
+}
+
+// formatValue renders a sample value, spelling out the special float values
+func formatValue(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
@@ -10,3 +10,27 @@ type ScaleDataRequest struct {
 	Unit  string  `json:"unit"`
 	Type  string  `json:"type"`
 }
+
+// Reading is a weight frame decoded by a protocol parser
+type Reading struct {
+	Value     float64  `json:"value"`
+	Unit      string   `json:"unit"`
+	Type      string   `json:"type"`
+	Gross     *float64 `json:"gross,omitempty"`
+	Net       *float64 `json:"net,omitempty"`
+	Tare      *float64 `json:"tare,omitempty"`
+	Stable    bool     `json:"stable"`
+	Overload  bool     `json:"overload"`
+	Underload bool     `json:"underload"`
+	Negative  bool     `json:"negative"`
+	Raw       string   `json:"raw"`
+}
+
+// ScaleData returns the reading in the legacy scale_data payload format
+func (r *Reading) ScaleData() *ScaleDataRequest {
+	return &ScaleDataRequest{
+		Value: r.Value,
+		Unit:  r.Unit,
+		Type:  r.Type,
+	}
+}
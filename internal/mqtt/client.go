@@ -0,0 +1,293 @@
+package mqtt
+
+import (
+	"bridge-serial/pkg/logger"
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNotConnected is returned by Publish and Subscribe while the broker is unreachable
+var ErrNotConnected = errors.New("not connected to MQTT broker")
+
+// connectTimeout bounds dialing the broker and waiting for CONNACK
+const connectTimeout = 10 * time.Second
+
+// Will is the message the broker publishes when the client disconnects ungracefully
+type Will struct {
+	Topic   string
+	Payload []byte
+	Retain  bool
+}
+
+// Message is a PUBLISH received on a subscribed topic
+type Message struct {
+	Topic   string
+	Payload []byte
+}
+
+// Options configures a Client
+type Options struct {
+	// Broker is the broker address as host:port or tcp://host:port
+	Broker   string
+	ClientID string
+	Username string
+	Password string
+
+	KeepAlive time.Duration
+	Will      *Will
+
+	// ReconnectInterval is the initial delay between connection attempts; it
+	// doubles on each failure up to MaxReconnectInterval
+	ReconnectInterval    time.Duration
+	MaxReconnectInterval time.Duration
+
+	// OnConnect runs after every successful connection, e.g. to publish an
+	// online status and subscribe, since sessions are not persisted
+	OnConnect func(c *Client)
+	// OnMessage receives messages on subscribed topics
+	OnMessage func(msg Message)
+}
+
+// Client is a minimal MQTT 3.1.1 client that publishes at QoS 0, subscribes
+// at QoS 0 and keeps reconnecting to the broker until stopped
+type Client struct {
+	opts Options
+
+	mu       sync.Mutex
+	conn     net.Conn
+	packetID uint16
+
+	// writeMu serializes packets written to conn
+	writeMu sync.Mutex
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewClient creates a client; call Start to connect
+func NewClient(opts Options) *Client {
+	if opts.KeepAlive <= 0 {
+		opts.KeepAlive = 30 * time.Second
+	}
+	if opts.ReconnectInterval <= 0 {
+		opts.ReconnectInterval = time.Second
+	}
+	return &Client{opts: opts}
+}
+
+// Start connects in the background and reconnects whenever the connection is lost
+func (c *Client) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+
+	c.wg.Add(1)
+	go c.run(ctx)
+}
+
+// Stop disconnects gracefully, so the broker does not publish the will
+func (c *Client) Stop() {
+	if conn := c.currentConn(); conn != nil {
+		c.write(conn, encodePacket(packetDisconnect, 0, nil))
+	}
+	if c.cancel != nil {
+		c.cancel()
+	}
+	c.wg.Wait()
+}
+
+// IsConnected reports whether the client holds an accepted broker connection
+func (c *Client) IsConnected() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conn != nil
+}
+
+// Publish sends a QoS 0 message
+func (c *Client) Publish(topic string, payload []byte, retain bool) error {
+	conn := c.currentConn()
+	if conn == nil {
+		return ErrNotConnected
+	}
+	return c.write(conn, encodePublish(topic, payload, retain))
+}
+
+// Subscribe requests QoS 0 delivery of messages matching the topic filters
+func (c *Client) Subscribe(filters ...string) error {
+	conn := c.currentConn()
+	if conn == nil {
+		return ErrNotConnected
+	}
+
+	c.mu.Lock()
+	c.packetID++
+	if c.packetID == 0 {
+		c.packetID = 1
+	}
+	id := c.packetID
+	c.mu.Unlock()
+
+	return c.write(conn, encodeSubscribe(id, filters))
+}
+
+func (c *Client) currentConn() net.Conn {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conn
+}
+
+func (c *Client) write(conn net.Conn, data []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	conn.SetWriteDeadline(time.Now().Add(c.opts.KeepAlive))
+	_, err := conn.Write(data)
+	return err
+}
+
+// run keeps a broker connection open until ctx is cancelled
+func (c *Client) run(ctx context.Context) {
+	defer c.wg.Done()
+
+	delay := c.opts.ReconnectInterval
+	for {
+		conn, reader, err := c.connect(ctx)
+		if err == nil {
+			logger.Info("connected to MQTT broker %s", c.opts.Broker)
+			delay = c.opts.ReconnectInterval
+
+			c.mu.Lock()
+			c.conn = conn
+			c.mu.Unlock()
+
+			if c.opts.OnConnect != nil {
+				c.opts.OnConnect(c)
+			}
+			err = c.serve(ctx, conn, reader)
+
+			c.mu.Lock()
+			c.conn = nil
+			c.mu.Unlock()
+			conn.Close()
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+		logger.Error("MQTT broker %s unavailable, retrying in %s: %v", c.opts.Broker, delay, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay *= 2
+		if c.opts.MaxReconnectInterval > 0 && delay > c.opts.MaxReconnectInterval {
+			delay = c.opts.MaxReconnectInterval
+		}
+	}
+}
+
+// connect dials the broker and completes the CONNECT/CONNACK handshake
+func (c *Client) connect(ctx context.Context) (net.Conn, *bufio.Reader, error) {
+	dialer := net.Dialer{Timeout: connectTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", strings.TrimPrefix(c.opts.Broker, "tcp://"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	conn.SetDeadline(time.Now().Add(connectTimeout))
+	if _, err := conn.Write(encodeConnect(&c.opts)); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+
+	reader := bufio.NewReader(conn)
+	p, err := readPacket(reader)
+	if err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("failed to read CONNACK: %w", err)
+	}
+	if p.kind != packetConnack || len(p.body) != 2 {
+		conn.Close()
+		return nil, nil, fmt.Errorf("expected CONNACK, got packet type %d", p.kind)
+	}
+	if code := p.body[1]; code != 0 {
+		conn.Close()
+		reason, ok := connackReasons[code]
+		if !ok {
+			reason = fmt.Sprintf("return code %d", code)
+		}
+		return nil, nil, fmt.Errorf("connection refused: %s", reason)
+	}
+
+	conn.SetDeadline(time.Time{})
+	return conn, reader, nil
+}
+
+// serve reads packets and sends keep-alive pings until the connection fails
+func (c *Client) serve(ctx context.Context, conn net.Conn, reader *bufio.Reader) error {
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		ticker := time.NewTicker(c.opts.KeepAlive * 3 / 4)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				conn.Close()
+				return
+			case <-ticker.C:
+				if err := c.write(conn, encodePacket(packetPingreq, 0, nil)); err != nil {
+					conn.Close()
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		// the broker answers every PINGREQ, so silence beyond the keep-alive means the link is dead
+		conn.SetReadDeadline(time.Now().Add(c.opts.KeepAlive * 3 / 2))
+		p, err := readPacket(reader)
+		if err != nil {
+			return err
+		}
+
+		switch p.kind {
+		case packetPublish:
+			c.handlePublish(conn, p)
+		case packetSuback:
+			if len(p.body) > 2 && p.body[2] == 0x80 {
+				logger.Error("MQTT broker rejected subscription")
+			}
+		case packetPingresp, packetPuback:
+		default:
+			logger.Debug("ignoring MQTT packet type %d", p.kind)
+		}
+	}
+}
+
+// handlePublish delivers an incoming message, acknowledging it when sent at QoS 1
+func (c *Client) handlePublish(conn net.Conn, p *packet) {
+	topic, payload, packetID, err := decodePublish(p)
+	if err != nil {
+		logger.Error("malformed MQTT PUBLISH: %v", err)
+		return
+	}
+	if packetID != 0 {
+		c.write(conn, encodePacket(packetPuback, 0, binary.BigEndian.AppendUint16(nil, packetID)))
+	}
+	if c.opts.OnMessage != nil {
+		c.opts.OnMessage(Message{Topic: topic, Payload: payload})
+	}
+}
@@ -0,0 +1,141 @@
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+)
+
+// testBroker is an in-process stand-in for an MQTT broker that accepts one
+// client, records the packets it sends and answers SUBSCRIBE with a PUBLISH
+// of command on the subscribed topic
+type testBroker struct {
+	listener net.Listener
+	command  []byte
+	packets  chan *packet
+}
+
+func newTestBroker(t *testing.T, command string) *testBroker {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &testBroker{listener: l, command: []byte(command), packets: make(chan *packet, 16)}
+	go b.serve()
+	t.Cleanup(func() { l.Close() })
+	return b
+}
+
+func (b *testBroker) serve() {
+	conn, err := b.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	for {
+		p, err := readPacket(reader)
+		if err != nil {
+			return
+		}
+		b.packets <- p
+
+		switch p.kind {
+		case packetConnect:
+			conn.Write(encodePacket(packetConnack, 0, []byte{0, 0}))
+		case packetSubscribe:
+			filter, _, err := readString(p.body[2:])
+			if err != nil {
+				return
+			}
+			conn.Write(encodePacket(packetSuback, 0, append(p.body[:2:2], 0)))
+			conn.Write(encodePublish(string(filter), b.command, false))
+		case packetPingreq:
+			conn.Write(encodePacket(packetPingresp, 0, nil))
+		}
+	}
+}
+
+// next returns the next packet of the given type the client sent
+func (b *testBroker) next(t *testing.T, kind byte) *packet {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case p := <-b.packets:
+			if p.kind == kind {
+				return p
+			}
+		case <-timeout:
+			t.Fatalf("no packet of type %d received", kind)
+		}
+	}
+}
+
+func TestClientAgainstBroker(t *testing.T) {
+	broker := newTestBroker(t, "tare")
+	messages := make(chan Message, 1)
+
+	client := NewClient(Options{
+		Broker:    "tcp://" + broker.listener.Addr().String(),
+		ClientID:  "bridge-serial",
+		KeepAlive: 30 * time.Second,
+		Will: &Will{
+			Topic:   "scales/bridge-serial/status",
+			Payload: []byte("offline"),
+			Retain:  true,
+		},
+		OnConnect: func(c *Client) {
+			if err := c.Publish("scales/ttyUSB0/weight", []byte(`{"value":12.11}`), true); err != nil {
+				t.Errorf("Publish: %v", err)
+			}
+			if err := c.Subscribe("scales/bridge-serial/command"); err != nil {
+				t.Errorf("Subscribe: %v", err)
+			}
+		},
+		OnMessage: func(msg Message) { messages <- msg },
+	})
+	client.Start()
+	defer client.Stop()
+
+	connect := broker.next(t, packetConnect)
+	protocolName, rest, err := readString(connect.body)
+	if err != nil || string(protocolName) != "MQTT" || rest[0] != protocolLevel311 {
+		t.Fatalf("CONNECT header = %q level %d, %v", protocolName, rest[0], err)
+	}
+	flags := rest[1]
+	if flags&flagWill == 0 || flags&flagWillRetain == 0 {
+		t.Errorf("CONNECT flags = %#x, want will with retain", flags)
+	}
+	if keepAlive := binary.BigEndian.Uint16(rest[2:]); keepAlive != 30 {
+		t.Errorf("keep alive = %d, want 30", keepAlive)
+	}
+	clientID, rest, _ := readString(rest[4:])
+	willTopic, rest, _ := readString(rest)
+	willPayload, _, _ := readString(rest)
+	if string(clientID) != "bridge-serial" || string(willTopic) != "scales/bridge-serial/status" || string(willPayload) != "offline" {
+		t.Errorf("CONNECT client %q will %q = %q", clientID, willTopic, willPayload)
+	}
+
+	publish := broker.next(t, packetPublish)
+	topic, payload, _, err := decodePublish(publish)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if topic != "scales/ttyUSB0/weight" || string(payload) != `{"value":12.11}` || publish.flags&0x01 == 0 {
+		t.Errorf("PUBLISH %s retain=%v: %s", topic, publish.flags&0x01 != 0, payload)
+	}
+
+	select {
+	case msg := <-messages:
+		if msg.Topic != "scales/bridge-serial/command" || string(msg.Payload) != "tare" {
+			t.Errorf("OnMessage got %s: %s", msg.Topic, msg.Payload)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("command did not reach OnMessage")
+	}
+}
@@ -0,0 +1,198 @@
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// MQTT 3.1.1 control packet types
+const (
+	packetConnect     byte = 1
+	packetConnack     byte = 2
+	packetPublish     byte = 3
+	packetPuback      byte = 4
+	packetSubscribe   byte = 8
+	packetSuback      byte = 9
+	packetPingreq     byte = 12
+	packetPingresp    byte = 13
+	packetDisconnect  byte = 14
+	protocolLevel311  byte = 4
+	maxRemainingBytes      = 1 << 20 // larger packets are not expected from a broker
+)
+
+// CONNECT flags
+const (
+	flagCleanSession byte = 0x02
+	flagWill         byte = 0x04
+	flagWillRetain   byte = 0x20
+	flagPassword     byte = 0x40
+	flagUsername     byte = 0x80
+)
+
+// connackReasons describes the CONNACK return codes
+var connackReasons = map[byte]string{
+	1: "unacceptable protocol version",
+	2: "client identifier rejected",
+	3: "server unavailable",
+	4: "bad user name or password",
+	5: "not authorized",
+}
+
+var errPacketTooLarge = errors.New("packet exceeds maximum size")
+
+// packet is a decoded control packet
+type packet struct {
+	kind  byte
+	flags byte
+	body  []byte
+}
+
+// readPacket reads one control packet
+func readPacket(r *bufio.Reader) (*packet, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	length, err := readRemainingLength(r)
+	if err != nil {
+		return nil, err
+	}
+	if length > maxRemainingBytes {
+		return nil, errPacketTooLarge
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	return &packet{kind: header >> 4, flags: header & 0x0f, body: body}, nil
+}
+
+// readRemainingLength decodes the variable length integer of the fixed header
+func readRemainingLength(r *bufio.Reader) (int, error) {
+	length := 0
+	for i := 0; i < 4; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		length |= int(b&0x7f) << (7 * i)
+		if b&0x80 == 0 {
+			return length, nil
+		}
+	}
+	return 0, fmt.Errorf("malformed remaining length")
+}
+
+// encodePacket builds a control packet from its fixed header and body
+func encodePacket(kind, flags byte, body []byte) []byte {
+	out := []byte{kind<<4 | flags&0x0f}
+	length := len(body)
+	for {
+		b := byte(length % 128)
+		length /= 128
+		if length > 0 {
+			b |= 0x80
+		}
+		out = append(out, b)
+		if length == 0 {
+			break
+		}
+	}
+	return append(out, body...)
+}
+
+// appendString appends a length prefixed UTF-8 string or binary field
+func appendString(buf []byte, s []byte) []byte {
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(s)))
+	return append(buf, s...)
+}
+
+// readString reads a length prefixed field and returns it with the remaining bytes
+func readString(buf []byte) ([]byte, []byte, error) {
+	if len(buf) < 2 {
+		return nil, nil, fmt.Errorf("truncated string length")
+	}
+	n := int(binary.BigEndian.Uint16(buf))
+	if len(buf) < 2+n {
+		return nil, nil, fmt.Errorf("truncated string")
+	}
+	return buf[2 : 2+n], buf[2+n:], nil
+}
+
+// encodeConnect builds a CONNECT packet for a clean session
+func encodeConnect(opts *Options) []byte {
+	flags := flagCleanSession
+	if opts.Will != nil {
+		flags |= flagWill
+		if opts.Will.Retain {
+			flags |= flagWillRetain
+		}
+	}
+	if opts.Username != "" {
+		flags |= flagUsername
+		if opts.Password != "" {
+			flags |= flagPassword
+		}
+	}
+
+	body := appendString(nil, []byte("MQTT"))
+	body = append(body, protocolLevel311, flags)
+	body = binary.BigEndian.AppendUint16(body, uint16(opts.KeepAlive.Seconds()))
+	body = appendString(body, []byte(opts.ClientID))
+	if opts.Will != nil {
+		body = appendString(body, []byte(opts.Will.Topic))
+		body = appendString(body, opts.Will.Payload)
+	}
+	if opts.Username != "" {
+		body = appendString(body, []byte(opts.Username))
+		if opts.Password != "" {
+			body = appendString(body, []byte(opts.Password))
+		}
+	}
+	return encodePacket(packetConnect, 0, body)
+}
+
+// encodePublish builds a QoS 0 PUBLISH packet
+func encodePublish(topic string, payload []byte, retain bool) []byte {
+	var flags byte
+	if retain {
+		flags = 0x01
+	}
+	body := appendString(nil, []byte(topic))
+	body = append(body, payload...)
+	return encodePacket(packetPublish, flags, body)
+}
+
+// encodeSubscribe builds a SUBSCRIBE packet requesting QoS 0 for each filter
+func encodeSubscribe(packetID uint16, filters []string) []byte {
+	body := binary.BigEndian.AppendUint16(nil, packetID)
+	for _, filter := range filters {
+		body = appendString(body, []byte(filter))
+		body = append(body, 0)
+	}
+	return encodePacket(packetSubscribe, 0x02, body)
+}
+
+// decodePublish returns the topic, payload and packet identifier of a PUBLISH.
+// The identifier is zero for QoS 0.
+func decodePublish(p *packet) (string, []byte, uint16, error) {
+	topic, rest, err := readString(p.body)
+	if err != nil {
+		return "", nil, 0, err
+	}
+
+	var packetID uint16
+	if qos := (p.flags >> 1) & 0x03; qos > 0 {
+		if len(rest) < 2 {
+			return "", nil, 0, fmt.Errorf("truncated packet identifier")
+		}
+		packetID = binary.BigEndian.Uint16(rest)
+		rest = rest[2:]
+	}
+	return string(topic), rest, packetID, nil
+}
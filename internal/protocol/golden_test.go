@@ -0,0 +1,117 @@
+package protocol
+
+import (
+	"bridge-serial/internal/model"
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// goldenCase is a captured frame and the reading or error it decodes to
+type goldenCase struct {
+	line  int
+	frame string
+	want  string // reading JSON without raw, empty when err is set
+	err   string
+}
+
+// readGolden reads a testdata file of
+//
+//	frame: "<Go quoted frame>"
+//	want: {"value":12.34,...} or error: <message>
+//
+// pairs; lines starting with # are comments
+func readGolden(t *testing.T, path string) []goldenCase {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var cases []goldenCase
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		key, value, _ := strings.Cut(line, ": ")
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+		case key == "frame":
+			frame, err := strconv.Unquote(value)
+			if err != nil {
+				t.Fatalf("%s:%d: %v", path, n, err)
+			}
+			cases = append(cases, goldenCase{line: n, frame: frame})
+		case (key == "want" || key == "error") && len(cases) > 0:
+			if key == "want" {
+				cases[len(cases)-1].want = value
+			} else {
+				cases[len(cases)-1].err = value
+			}
+		default:
+			t.Fatalf("%s:%d: unexpected line %q", path, n, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return cases
+}
+
+// TestGolden decodes the frames in testdata/<parser>/*.txt with the parser
+// registered under the directory name
+func TestGolden(t *testing.T) {
+	dirs, err := os.ReadDir("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range dirs {
+		parser, err := New(dir.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		files, err := filepath.Glob(filepath.Join("testdata", dir.Name(), "*.txt"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, path := range files {
+			t.Run(dir.Name()+"/"+filepath.Base(path), func(t *testing.T) {
+				for _, tc := range readGolden(t, path) {
+					checkGolden(t, path, parser, tc)
+				}
+			})
+		}
+	}
+}
+
+func checkGolden(t *testing.T, path string, parser Parser, tc goldenCase) {
+	t.Helper()
+	reading, err := parser.Parse(tc.frame)
+
+	if tc.err != "" {
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%s:%d: Parse(%q) error = %v, want %q", path, tc.line, tc.frame, err, tc.err)
+		}
+		return
+	}
+	if err != nil {
+		t.Errorf("%s:%d: Parse(%q): %v", path, tc.line, tc.frame, err)
+		return
+	}
+
+	var want model.Reading
+	if err := json.Unmarshal([]byte(tc.want), &want); err != nil {
+		t.Fatalf("%s:%d: %v", path, tc.line, err)
+	}
+	want.Raw = tc.frame
+
+	got, _ := json.Marshal(reading)
+	expected, _ := json.Marshal(&want)
+	if string(got) != string(expected) {
+		t.Errorf("%s:%d: Parse(%q)\n got %s\nwant %s", path, tc.line, tc.frame, got, expected)
+	}
+}
@@ -0,0 +1,146 @@
+package protocol
+
+import (
+	"bridge-serial/internal/model"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	Register("mt-sics", func() Parser { return MTSICSParser{} })
+}
+
+// MTSICSError is an error reply from a Mettler Toledo MT-SICS device
+type MTSICSError struct {
+	Command string // command the reply belongs to, empty for ES/ET/EL
+	Status  string
+	Reason  string
+}
+
+func (e *MTSICSError) Error() string {
+	if e.Command == "" {
+		return fmt.Sprintf("mt-sics %s: %s", e.Status, e.Reason)
+	}
+	return fmt.Sprintf("mt-sics %s %s: %s", e.Command, e.Status, e.Reason)
+}
+
+// Unwrap returns ErrDeviceError, so error replies are not parse failures
+func (e *MTSICSError) Unwrap() error {
+	return ErrDeviceError
+}
+
+// mtsicsCommands maps commands to their MT-SICS level 0/1 encoding
+var mtsicsCommands = map[Command]string{
+	CommandWeighStable: "S",
+	CommandWeigh:       "SI",
+	CommandTare:        "T",
+	CommandZero:        "Z",
+	CommandTareValue:   "TA",
+	CommandPrint:       "S",
+}
+
+// MTSICSParser decodes Mettler Toledo MT-SICS replies such as
+// "S S     12.34 g" (stable) or "S D     12.30 g" (dynamic).
+type MTSICSParser struct{}
+
+// Parse decodes a reply line. S and SI weight replies become readings, error
+// replies become *MTSICSError wrapping ErrDeviceError, and acknowledgements as well as the tare
+// replies "T S   100.00 g" and "TA A   100.00 g" wrap ErrNotWeight, since
+// the weight they carry is the tare and not the load on the scale.
+func (MTSICSParser) Parse(line string) (*model.Reading, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("invalid mt-sics reply: %q", line)
+	}
+
+	id := fields[0]
+	switch id {
+	case "ES":
+		return nil, &MTSICSError{Status: id, Reason: "syntax error"}
+	case "ET":
+		return nil, &MTSICSError{Status: id, Reason: "transmission error"}
+	case "EL":
+		return nil, &MTSICSError{Status: id, Reason: "logical error"}
+	}
+
+	if len(fields) < 2 {
+		return nil, fmt.Errorf("invalid mt-sics reply: %q", line)
+	}
+
+	status := fields[1]
+	switch status {
+	case "I":
+		return nil, &MTSICSError{Command: id, Status: status, Reason: "command not executable"}
+	case "L":
+		return nil, &MTSICSError{Command: id, Status: status, Reason: "command understood but parameter wrong"}
+	case "+", "-":
+		if id == "S" || id == "SI" {
+			return &model.Reading{
+				Type:      id,
+				Overload:  status == "+",
+				Underload: status == "-",
+				Raw:       line,
+			}, nil
+		}
+		return nil, &MTSICSError{Command: id, Status: status, Reason: "range limit exceeded"}
+	}
+
+	switch id {
+	case "S", "SI":
+		return parseMTSICSWeight(id, status, fields, line)
+	default:
+		// T S, TA A, Z A, I4 A "0123456789" and similar acknowledgements
+		return nil, fmt.Errorf("%w: %s", ErrNotWeight, line)
+	}
+}
+
+// parseMTSICSWeight decodes "<ID> <Status> <Value> <Unit>" replies
+func parseMTSICSWeight(id, status string, fields []string, line string) (*model.Reading, error) {
+	if status != "S" && status != "D" {
+		return nil, fmt.Errorf("invalid mt-sics status %q in reply: %q", status, line)
+	}
+	if len(fields) < 4 {
+		return nil, fmt.Errorf("invalid mt-sics weight reply: %q", line)
+	}
+
+	value, err := strconv.ParseFloat(fields[2], 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse value '%s' from mt-sics reply: %v", fields[2], err)
+	}
+
+	return &model.Reading{
+		Value:    value,
+		Unit:     fields[3],
+		Type:     id,
+		Net:      &value,
+		Stable:   status != "D",
+		Negative: value < 0,
+		Raw:      line,
+	}, nil
+}
+
+// MatchesReply reports whether reply answers cmd. MT-SICS replies start with
+// the command name, or with ES, ET or EL when the command was not understood.
+func (MTSICSParser) MatchesReply(cmd []byte, reply string) bool {
+	sent := strings.Fields(string(cmd))
+	fields := strings.Fields(reply)
+	if len(sent) == 0 || len(fields) == 0 {
+		return false
+	}
+
+	switch fields[0] {
+	case "ES", "ET", "EL":
+		return true
+	}
+	return fields[0] == sent[0]
+}
+
+// Encode returns the MT-SICS command terminated by CR LF
+func (MTSICSParser) Encode(cmd Command) ([]byte, error) {
+	code, ok := mtsicsCommands[cmd]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedCommand, cmd)
+	}
+	return []byte(code + "\r\n"), nil
+}
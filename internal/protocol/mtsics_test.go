@@ -0,0 +1,76 @@
+package protocol
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMTSICSTareRepliesCarryNoWeight(t *testing.T) {
+	for _, line := range []string{"T S   100.00 g", "TA A   100.00 g", "Z A"} {
+		reading, err := MTSICSParser{}.Parse(line)
+		if !errors.Is(err, ErrNotWeight) {
+			t.Errorf("Parse(%q) = %+v, %v, want ErrNotWeight", line, reading, err)
+		}
+	}
+}
+
+func TestMTSICSWeightReplies(t *testing.T) {
+	tests := []struct {
+		line   string
+		value  float64
+		stable bool
+	}{
+		{"S S     12.34 g", 12.34, true},
+		{"SI D     12.30 g", 12.30, false},
+		{"S S     -0.50 g", -0.50, true},
+	}
+	for _, tt := range tests {
+		reading, err := MTSICSParser{}.Parse(tt.line)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.line, err)
+			continue
+		}
+		if reading.Value != tt.value || reading.Unit != "g" || reading.Stable != tt.stable {
+			t.Errorf("Parse(%q) = %+v", tt.line, reading)
+		}
+		if reading.Net == nil || *reading.Net != tt.value || reading.Tare != nil {
+			t.Errorf("Parse(%q) net/tare = %v/%v", tt.line, reading.Net, reading.Tare)
+		}
+	}
+}
+
+func TestMTSICSErrorRepliesAreDeviceErrors(t *testing.T) {
+	for _, line := range []string{"ES", "ET", "EL", "S I", "T L", "Z +"} {
+		reading, err := MTSICSParser{}.Parse(line)
+		var replyErr *MTSICSError
+		if !errors.As(err, &replyErr) || !errors.Is(err, ErrDeviceError) || !errors.Is(err, ErrNotWeight) {
+			t.Errorf("Parse(%q) = %+v, %v, want a device error", line, reading, err)
+		}
+	}
+
+	if _, err := (MTSICSParser{}).Parse("S S"); errors.Is(err, ErrNotWeight) {
+		t.Errorf("truncated weight reply %v is not a parse failure", err)
+	}
+}
+
+func TestMTSICSMatchesReply(t *testing.T) {
+	tests := []struct {
+		cmd   string
+		reply string
+		want  bool
+	}{
+		{"SI\r\n", "SI S     12.30 g", true},
+		{"SI\r\n", "SI +", true},
+		{"SI\r\n", "S S     12.30 g", false},
+		{"S\r\n", "SI S     12.30 g", false},
+		{"T\r\n", "T S    100.00 g", true},
+		{"SI\r\n", "ES", true},
+		{"SI\r\n", "Z A", false},
+		{"SI\r\n", "", false},
+	}
+	for _, tt := range tests {
+		if got := (MTSICSParser{}).MatchesReply([]byte(tt.cmd), tt.reply); got != tt.want {
+			t.Errorf("MatchesReply(%q, %q) = %v, want %v", tt.cmd, tt.reply, got, tt.want)
+		}
+	}
+}
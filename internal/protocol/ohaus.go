@@ -0,0 +1,102 @@
+package protocol
+
+import (
+	"bridge-serial/internal/model"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	Register("ohaus", func() Parser { return OhausParser{} })
+}
+
+// ohausCommands maps commands to their Ohaus interface commands
+var ohausCommands = map[Command]string{
+	CommandWeigh:       "IP",
+	CommandWeighStable: "SP",
+	CommandPrint:       "P",
+	CommandTare:        "T",
+	CommandZero:        "Z",
+	CommandTareValue:   "PT",
+}
+
+// OhausParser decodes Ohaus print output such as "     12.34 g  ?  N",
+// where "?" marks an unstable weight and N/G/T identify net, gross and tare.
+type OhausParser struct{}
+
+// Parse decodes a print line. "ES" replies wrap ErrDeviceError and "OK!"
+// acknowledgements wrap ErrNotWeight.
+func (OhausParser) Parse(line string) (*model.Reading, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("invalid ohaus frame: %q", line)
+	}
+
+	switch fields[0] {
+	case "ES":
+		return nil, fmt.Errorf("ohaus ES: invalid command: %w", ErrDeviceError)
+	case "OK!":
+		return nil, fmt.Errorf("%w: %s", ErrNotWeight, line)
+	}
+
+	reading := &model.Reading{Stable: true, Raw: line}
+
+	sign := ""
+	if fields[0] == "+" || fields[0] == "-" {
+		sign = fields[0]
+		fields = fields[1:]
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("invalid ohaus frame: %q", line)
+	}
+
+	switch sign + fields[0] {
+	case "OL", "+OL":
+		reading.Overload = true
+		return reading, nil
+	case "UL", "-OL":
+		reading.Underload = true
+		return reading, nil
+	}
+
+	value, err := strconv.ParseFloat(sign+fields[0], 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse value '%s' from ohaus frame: %v", fields[0], err)
+	}
+	reading.Value = value
+	reading.Negative = value < 0
+
+	// The unit directly follows the value, the markers come after it
+	markers := fields[1:]
+	if len(markers) > 0 && markers[0] != "?" {
+		reading.Unit = markers[0]
+		markers = markers[1:]
+	}
+
+	for _, field := range markers {
+		switch strings.ToUpper(field) {
+		case "?":
+			reading.Stable = false
+		case "N", "NET":
+			reading.Type = "N"
+			reading.Net = &value
+		case "G", "GROSS":
+			reading.Type = "G"
+			reading.Gross = &value
+		case "T", "TARE", "PT":
+			reading.Type = "T"
+			reading.Tare = &value
+		}
+	}
+	return reading, nil
+}
+
+// Encode returns the Ohaus command terminated by CR LF
+func (OhausParser) Encode(cmd Command) ([]byte, error) {
+	code, ok := ohausCommands[cmd]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedCommand, cmd)
+	}
+	return []byte(code + "\r\n"), nil
+}
@@ -0,0 +1,106 @@
+package protocol
+
+import (
+	"bridge-serial/internal/model"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// DefaultParser is the parser used when the config does not name one
+const DefaultParser = "whitespace-fields"
+
+// Parser decodes one line received from a scale into a reading
+type Parser interface {
+	Parse(line string) (*model.Reading, error)
+}
+
+// Factory creates a new parser instance
+type Factory func() Parser
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Factory)
+)
+
+// Register makes a parser available under the given name. It panics if the
+// name is already taken, so conflicting registrations fail at startup.
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, exists := registry[name]; exists {
+		panic(fmt.Sprintf("protocol: parser %q registered twice", name))
+	}
+	registry[name] = factory
+}
+
+// New creates the parser registered under name, or the default parser when name is empty
+func New(name string) (Parser, error) {
+	if name == "" {
+		name = DefaultParser
+	}
+
+	registryMu.RLock()
+	factory, ok := registry[name]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown protocol parser %q (available: %v)", name, Names())
+	}
+	return factory(), nil
+}
+
+// Names returns the registered parser names in sorted order
+func Names() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Command is a protocol independent request sent to a scale
+type Command string
+
+const (
+	CommandWeigh       Command = "weigh"        // send the current weight immediately
+	CommandWeighStable Command = "weigh_stable" // send the next stable weight
+	CommandTare        Command = "tare"
+	CommandZero        Command = "zero"
+	CommandPrint       Command = "print"
+	CommandTareValue   Command = "tare_value" // query the stored tare weight
+)
+
+// ErrUnsupportedCommand is returned when a protocol has no encoding for a command
+var ErrUnsupportedCommand = errors.New("command not supported by protocol")
+
+// ErrNotWeight is returned by parsers for well-formed frames that carry no
+// weight, such as command acknowledgements or identification replies.
+var ErrNotWeight = errors.New("frame carries no weight")
+
+// ErrDeviceError is wrapped by error replies from the scale, such as a
+// rejected command. These frames are well-formed, so they match ErrNotWeight
+// as well and are not counted as parse failures.
+var ErrDeviceError error = deviceError{}
+
+type deviceError struct{}
+
+func (deviceError) Error() string { return "device reported an error" }
+
+func (deviceError) Is(target error) bool { return target == ErrNotWeight }
+
+// Commander is implemented by parsers whose scales accept commands
+type Commander interface {
+	Encode(cmd Command) ([]byte, error)
+}
+
+// ReplyMatcher is implemented by parsers whose replies name the command they
+// answer, which tells a reply apart from stale or unsolicited lines
+type ReplyMatcher interface {
+	MatchesReply(cmd []byte, reply string) bool
+}
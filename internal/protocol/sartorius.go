@@ -0,0 +1,103 @@
+package protocol
+
+import (
+	"bridge-serial/internal/model"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	Register("sartorius-sbi", func() Parser { return SartoriusParser{} })
+}
+
+// sartoriusCommands maps commands to their SBI escape sequences
+var sartoriusCommands = map[Command]string{
+	CommandWeigh: "\x1bP",
+	CommandPrint: "\x1bP",
+	CommandTare:  "\x1bf4_",
+	CommandZero:  "\x1bf3_",
+}
+
+// SartoriusParser decodes the Sartorius SBI print format, either the 16
+// character form "+     12.34 g  " or the 22 character form with a
+// 6 character identifier such as "N     +     12.34 g  " (both counts include
+// the CR LF). The unit is left blank while the balance is unstable.
+type SartoriusParser struct{}
+
+// Parse decodes a 16 or 22 character SBI frame
+func (SartoriusParser) Parse(line string) (*model.Reading, error) {
+	frame := strings.TrimRight(line, "\r\n")
+	if strings.TrimSpace(frame) == "" {
+		return nil, fmt.Errorf("invalid sartorius frame: %q", line)
+	}
+
+	id := ""
+	body := frame
+	if len(frame) > 6 && !isSartoriusValueStart(frame[0]) {
+		id = strings.TrimSpace(frame[:6])
+		body = frame[6:]
+	}
+
+	fields := strings.Fields(body)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("invalid sartorius frame: %q", line)
+	}
+
+	reading := &model.Reading{Type: id, Raw: line}
+
+	// The sign may be separated from the digits by padding
+	sign := ""
+	if fields[0] == "+" || fields[0] == "-" {
+		sign = fields[0]
+		fields = fields[1:]
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("invalid sartorius frame: %q", line)
+	}
+
+	switch strings.ToLower(fields[0]) {
+	case "high", "h":
+		reading.Overload = true
+		return reading, nil
+	case "low", "l":
+		reading.Underload = true
+		return reading, nil
+	}
+
+	value, err := strconv.ParseFloat(sign+fields[0], 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse value '%s' from sartorius frame: %v", fields[0], err)
+	}
+
+	reading.Value = value
+	reading.Negative = value < 0
+	if len(fields) > 1 {
+		reading.Unit = fields[1]
+		reading.Stable = true
+	}
+
+	switch id {
+	case "N", "NET":
+		reading.Net = &value
+	case "G", "G#":
+		reading.Gross = &value
+	case "T", "T1", "T2", "PT":
+		reading.Tare = &value
+	}
+	return reading, nil
+}
+
+// Encode returns the SBI escape sequence for the command terminated by CR LF
+func (SartoriusParser) Encode(cmd Command) ([]byte, error) {
+	code, ok := sartoriusCommands[cmd]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedCommand, cmd)
+	}
+	return []byte(code + "\r\n"), nil
+}
+
+// isSartoriusValueStart reports whether c can start the 16 character value block
+func isSartoriusValueStart(c byte) bool {
+	return c == '+' || c == '-' || c == ' ' || c == '.' || (c >= '0' && c <= '9')
+}
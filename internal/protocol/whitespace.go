@@ -0,0 +1,41 @@
+package protocol
+
+import (
+	"bridge-serial/internal/model"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	Register(DefaultParser, func() Parser { return WhitespaceParser{} })
+}
+
+// WhitespaceParser parses frames such as "WTST   12.11   g" or "WTUS    0.84   g"
+// Format: [PREFIX][SPACES][VALUE][SPACES][UNIT]
+type WhitespaceParser struct{}
+
+// Parse splits the line on whitespace and takes the last two fields as value and unit
+func (WhitespaceParser) Parse(line string) (*model.Reading, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return nil, fmt.Errorf("invalid scale data format: %s", line)
+	}
+
+	valueStr := fields[len(fields)-2]
+	value, err := strconv.ParseFloat(valueStr, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse value '%s' from scale data: %v", valueStr, err)
+	}
+
+	dataType := fields[0]
+	return &model.Reading{
+		Value:    value,
+		Unit:     fields[len(fields)-1],
+		Type:     dataType,
+		Stable:   !strings.HasSuffix(dataType, "US"),
+		Overload: strings.HasSuffix(dataType, "OL"),
+		Negative: value < 0,
+		Raw:      line,
+	}, nil
+}
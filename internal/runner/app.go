@@ -3,6 +3,7 @@ package runner
 import (
 	"bridge-serial/config"
 	"bridge-serial/internal/bridge"
+	"bridge-serial/internal/serial"
 	"bridge-serial/pkg/logger"
 
 	"fyne.io/fyne/v2"
@@ -15,6 +16,7 @@ type App struct {
 	config *config.Config
 
 	bridgeManager *bridge.BridgeManager
+	watcher       *config.Watcher
 
 	// ui related
 	window        fyne.Window
@@ -27,13 +29,18 @@ func NewApp(cfg *config.Config) (*App, error) {
 	myApp := app.New()
 	myApp.SetIcon(nil)
 
+	opener, err := serial.NewPortOpener(&cfg.SerialBridge)
+	if err != nil {
+		return nil, err
+	}
+
 	window := myApp.NewWindow(cfg.App.WindowTitle)
 	window.Resize(fyne.NewSize(400, 200))
 
 	return &App{
 		config:        cfg,
 		window:        window,
-		bridgeManager: bridge.NewBridgeManager(cfg),
+		bridgeManager: bridge.NewBridgeManager(cfg, opener),
 	}, nil
 }
 
@@ -42,6 +49,20 @@ func (a *App) Run() {
 	a.window.SetContent(content)
 	a.window.ShowAndRun()
 	logger.Info("Application started")
+
+	if a.watcher != nil {
+		a.watcher.Close()
+	}
+}
+
+// WatchConfig applies edits of the config file to the bridge while the app runs
+func (a *App) WatchConfig(opts config.LoadOptions) error {
+	watcher, err := a.bridgeManager.WatchConfig(opts)
+	if err != nil {
+		return err
+	}
+	a.watcher = watcher
+	return nil
 }
 
 func (a *App) onStartClick() {
@@ -0,0 +1,82 @@
+package serial
+
+import (
+	"bridge-serial/config"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"go.bug.st/serial/enumerator"
+)
+
+// ErrNoMatchingDevice is returned when no enumerated port matches the configured rules
+var ErrNoMatchingDevice = errors.New("no matching device")
+
+// matchPort returns the first port matching the rules, trying rules in ascending priority
+func matchPort(rules []config.DeviceMatchRule, ports []*enumerator.PortDetails) (*enumerator.PortDetails, error) {
+	ordered := make([]config.DeviceMatchRule, len(rules))
+	copy(ordered, rules)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].Priority < ordered[j].Priority
+	})
+
+	for _, rule := range ordered {
+		for _, port := range ports {
+			if ruleMatches(rule, port) {
+				return port, nil
+			}
+		}
+	}
+
+	enumerated := make([]string, 0, len(ports))
+	for _, port := range ports {
+		enumerated = append(enumerated, describePort(port))
+	}
+	if len(enumerated) == 0 {
+		enumerated = append(enumerated, "none")
+	}
+	return nil, fmt.Errorf("%w (rules: %d, enumerated: %s)", ErrNoMatchingDevice, len(rules), strings.Join(enumerated, ", "))
+}
+
+// ruleMatches reports whether every non-empty field of the rule matches the port
+func ruleMatches(rule config.DeviceMatchRule, port *enumerator.PortDetails) bool {
+	if rule.PortName == "" && rule.VID == "" && rule.PID == "" && rule.SerialNumber == "" && rule.Product == "" {
+		return false
+	}
+	if rule.PortName != "" && rule.PortName != port.Name {
+		return false
+	}
+	if rule.VID != "" && (!port.IsUSB || !strings.EqualFold(rule.VID, port.VID)) {
+		return false
+	}
+	if rule.PID != "" && (!port.IsUSB || !strings.EqualFold(rule.PID, port.PID)) {
+		return false
+	}
+	if rule.SerialNumber != "" && rule.SerialNumber != port.SerialNumber {
+		return false
+	}
+	if rule.Product != "" {
+		ok, err := path.Match(strings.ToLower(rule.Product), strings.ToLower(port.Product))
+		if err != nil || !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// describePort formats a port for error messages
+func describePort(port *enumerator.PortDetails) string {
+	if !port.IsUSB {
+		return port.Name
+	}
+	desc := fmt.Sprintf("%s [USB %s:%s", port.Name, port.VID, port.PID)
+	if port.SerialNumber != "" {
+		desc += fmt.Sprintf(" serial=%s", port.SerialNumber)
+	}
+	if port.Product != "" {
+		desc += fmt.Sprintf(" product=%q", port.Product)
+	}
+	return desc + "]"
+}
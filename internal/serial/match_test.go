@@ -0,0 +1,111 @@
+package serial
+
+import (
+	"bridge-serial/config"
+	"errors"
+	"testing"
+
+	"go.bug.st/serial/enumerator"
+)
+
+func TestMatchPort(t *testing.T) {
+	ports := []*enumerator.PortDetails{
+		{Name: "/dev/ttyS0"},
+		{Name: "/dev/ttyUSB0", IsUSB: true, VID: "0403", PID: "6001", SerialNumber: "A10K1", Product: "FT232R USB UART"},
+		{Name: "/dev/ttyUSB1", IsUSB: true, VID: "067b", PID: "2303", SerialNumber: "B20"},
+	}
+
+	tests := []struct {
+		name  string
+		rules []config.DeviceMatchRule
+		want  string // empty when no port matches
+	}{
+		{
+			name:  "port name",
+			rules: []config.DeviceMatchRule{{PortName: "/dev/ttyS0"}},
+			want:  "/dev/ttyS0",
+		},
+		{
+			name:  "vid and pid ignore case",
+			rules: []config.DeviceMatchRule{{VID: "067B", PID: "2303"}},
+			want:  "/dev/ttyUSB1",
+		},
+		{
+			name: "lowest priority wins over rule order",
+			rules: []config.DeviceMatchRule{
+				{Priority: 2, VID: "067B", PID: "2303"},
+				{Priority: 1, VID: "0403", PID: "6001"},
+			},
+			want: "/dev/ttyUSB0",
+		},
+		{
+			name: "equal priority keeps rule order",
+			rules: []config.DeviceMatchRule{
+				{Priority: 1, VID: "067B"},
+				{Priority: 1, VID: "0403"},
+			},
+			want: "/dev/ttyUSB1",
+		},
+		{
+			name:  "serial number",
+			rules: []config.DeviceMatchRule{{SerialNumber: "B20"}},
+			want:  "/dev/ttyUSB1",
+		},
+		{
+			name:  "serial number must match exactly",
+			rules: []config.DeviceMatchRule{{VID: "0403", SerialNumber: "a10k1"}},
+		},
+		{
+			name:  "product glob",
+			rules: []config.DeviceMatchRule{{Product: "*ft232*"}},
+			want:  "/dev/ttyUSB0",
+		},
+		{
+			name:  "every field must match",
+			rules: []config.DeviceMatchRule{{VID: "0403", PID: "2303"}},
+		},
+		{
+			name:  "vid requires a USB port",
+			rules: []config.DeviceMatchRule{{PortName: "/dev/ttyS0", VID: "0403"}},
+		},
+		{
+			name:  "empty rule matches nothing",
+			rules: []config.DeviceMatchRule{{Priority: 1}},
+		},
+		{
+			name: "no rules",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			port, err := matchPort(tt.rules, ports)
+			if tt.want == "" {
+				if !errors.Is(err, ErrNoMatchingDevice) {
+					t.Fatalf("matchPort = %v, %v, want ErrNoMatchingDevice", port, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if port.Name != tt.want {
+				t.Fatalf("matchPort = %s, want %s", port.Name, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchPortListsEnumeratedPorts(t *testing.T) {
+	_, err := matchPort([]config.DeviceMatchRule{{VID: "1A86"}}, []*enumerator.PortDetails{
+		{Name: "/dev/ttyUSB0", IsUSB: true, VID: "0403", PID: "6001"},
+	})
+	if want := "no matching device (rules: 1, enumerated: /dev/ttyUSB0 [USB 0403:6001])"; err == nil || err.Error() != want {
+		t.Fatalf("error = %v, want %q", err, want)
+	}
+
+	_, err = matchPort(nil, nil)
+	if want := "no matching device (rules: 0, enumerated: none)"; err == nil || err.Error() != want {
+		t.Fatalf("error = %v, want %q", err, want)
+	}
+}
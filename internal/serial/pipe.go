@@ -0,0 +1,66 @@
+package serial
+
+import (
+	"bridge-serial/config"
+	"net"
+	"sync"
+
+	"go.bug.st/serial/enumerator"
+)
+
+// PipeOpener is an in-memory transport for running the bridge without hardware.
+// Each Open creates a new pipe whose device end is handed out by Accept.
+type PipeOpener struct {
+	name    string
+	devices chan net.Conn
+
+	mu      sync.Mutex
+	present bool
+}
+
+// NewPipeOpener creates an in-memory opener exposing a single port with the given name
+func NewPipeOpener(name string) *PipeOpener {
+	return &PipeOpener{
+		name:    name,
+		devices: make(chan net.Conn, 1),
+		present: true,
+	}
+}
+
+// ListPorts returns the pipe port while it is plugged in
+func (o *PipeOpener) ListPorts() ([]*enumerator.PortDetails, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if !o.present {
+		return nil, nil
+	}
+	return []*enumerator.PortDetails{{Name: o.name}}, nil
+}
+
+// Open creates a new pipe and queues its device end for Accept
+func (o *PipeOpener) Open(name string, cfg *config.SerialBridgeConfig) (Port, error) {
+	bridgeEnd, deviceEnd := net.Pipe()
+
+	// Drop a device end nobody accepted so the newest connection wins
+	select {
+	case stale := <-o.devices:
+		stale.Close()
+	default:
+	}
+	o.devices <- deviceEnd
+
+	return &connPort{conn: bridgeEnd}, nil
+}
+
+// Accept waits for the bridge to open the port and returns the device end
+func (o *PipeOpener) Accept() net.Conn {
+	return <-o.devices
+}
+
+// SetPresent simulates plugging the device in or out
+func (o *PipeOpener) SetPresent(present bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.present = present
+}
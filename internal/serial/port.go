@@ -0,0 +1,155 @@
+package serial
+
+import (
+	"bridge-serial/config"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"time"
+
+	"go.bug.st/serial/enumerator"
+)
+
+// Port is an open byte stream to a scale
+type Port interface {
+	io.ReadWriteCloser
+
+	// SetReadTimeout sets how long Read waits for data. A Read that times
+	// out returns ErrReadTimeout; a non-positive duration disables the timeout.
+	SetReadTimeout(t time.Duration) error
+
+	// ResetInputBuffer discards data that was received but not read yet
+	ResetInputBuffer() error
+}
+
+// PortOpener enumerates and opens ports for a SerialBridge
+type PortOpener interface {
+	// ListPorts returns the ports that can currently be opened
+	ListPorts() ([]*enumerator.PortDetails, error)
+
+	// Open opens the named port using the line settings from cfg
+	Open(name string, cfg *config.SerialBridgeConfig) (Port, error)
+}
+
+// directOpener is implemented by openers that reach a single configured
+// port, such as a device server address. That port is opened as is, since
+// the device match rules describe USB adapters the opener cannot enumerate.
+type directOpener interface {
+	PortOpener
+
+	// directPort returns the port to open, empty when none is configured
+	directPort() string
+}
+
+// NewPortOpener returns the opener for the transport selected in the config
+func NewPortOpener(cfg *config.SerialBridgeConfig) (PortOpener, error) {
+	switch cfg.Transport {
+	case "", "serial":
+		return NewSystemOpener(), nil
+	case "tcp":
+		return NewTCPOpener(cfg.TCPAddress), nil
+	default:
+		return nil, fmt.Errorf("unknown serial transport: %s", cfg.Transport)
+	}
+}
+
+// connPort adapts a net.Conn or os.File to Port using read deadlines
+type connPort struct {
+	conn interface {
+		io.ReadWriteCloser
+		SetReadDeadline(t time.Time) error
+	}
+	timeout time.Duration
+}
+
+func (p *connPort) Read(b []byte) (int, error) {
+	if p.timeout > 0 {
+		if err := p.conn.SetReadDeadline(time.Now().Add(p.timeout)); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := p.conn.Read(b)
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		if n > 0 {
+			return n, nil
+		}
+		return 0, ErrReadTimeout
+	}
+	return n, err
+}
+
+func (p *connPort) Write(b []byte) (int, error) {
+	return p.conn.Write(b)
+}
+
+func (p *connPort) Close() error {
+	return p.conn.Close()
+}
+
+const (
+	// drainTimeout is how long ResetInputBuffer waits for more stale input
+	drainTimeout = time.Millisecond
+	// maxDrainTime bounds ResetInputBuffer on a device that prints continuously
+	maxDrainTime = 50 * time.Millisecond
+)
+
+// ResetInputBuffer reads and discards until no data arrives within drainTimeout
+func (p *connPort) ResetInputBuffer() error {
+	defer p.SetReadTimeout(p.timeout)
+
+	buf := make([]byte, 256)
+	for stop := time.Now().Add(maxDrainTime); time.Now().Before(stop); {
+		if err := p.conn.SetReadDeadline(time.Now().Add(drainTimeout)); err != nil {
+			return err
+		}
+		if _, err := p.conn.Read(buf); err != nil {
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *connPort) SetReadTimeout(t time.Duration) error {
+	p.timeout = t
+	if t <= 0 {
+		return p.conn.SetReadDeadline(time.Time{})
+	}
+	return nil
+}
+
+// TCPOpener connects to a serial device server such as ser2net over TCP
+type TCPOpener struct {
+	address string
+}
+
+// NewTCPOpener creates an opener for the given host:port address
+func NewTCPOpener(address string) *TCPOpener {
+	return &TCPOpener{address: address}
+}
+
+// ListPorts returns the configured address as the only port
+func (o *TCPOpener) ListPorts() ([]*enumerator.PortDetails, error) {
+	if o.address == "" {
+		return nil, nil
+	}
+	return []*enumerator.PortDetails{{Name: o.address}}, nil
+}
+
+func (o *TCPOpener) directPort() string {
+	return o.address
+}
+
+// Open dials the device server; the line settings are owned by the server
+func (o *TCPOpener) Open(name string, cfg *config.SerialBridgeConfig) (Port, error) {
+	conn, err := net.DialTimeout("tcp", name, cfg.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	return &connPort{conn: conn}, nil
+}
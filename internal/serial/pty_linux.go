@@ -0,0 +1,123 @@
+//go:build linux
+
+package serial
+
+import (
+	"bridge-serial/config"
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+
+	"go.bug.st/serial/enumerator"
+)
+
+// PTYOpener exposes the slave side of a Linux pseudo-terminal as a serial
+// port. Whatever is written to Master arrives at the bridge as if a scale
+// had sent it, which makes it possible to run the real read path without hardware.
+type PTYOpener struct {
+	master    *os.File
+	slaveName string
+}
+
+// NewPTYOpener allocates a new pseudo-terminal pair
+func NewPTYOpener() (*PTYOpener, error) {
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open /dev/ptmx: %w", err)
+	}
+
+	var ptn uint32
+	unlock := int32(0)
+	err = control(master, func(fd uintptr) error {
+		if err := ioctl(fd, syscall.TIOCSPTLCK, uintptr(unsafe.Pointer(&unlock))); err != nil {
+			return fmt.Errorf("failed to unlock pty: %w", err)
+		}
+		if err := ioctl(fd, syscall.TIOCGPTN, uintptr(unsafe.Pointer(&ptn))); err != nil {
+			return fmt.Errorf("failed to get pty number: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		master.Close()
+		return nil, err
+	}
+
+	return &PTYOpener{
+		master:    master,
+		slaveName: fmt.Sprintf("/dev/pts/%d", ptn),
+	}, nil
+}
+
+// Master returns the device side of the pseudo-terminal
+func (o *PTYOpener) Master() *os.File {
+	return o.master
+}
+
+// SlaveName returns the port name the bridge should be configured to open
+func (o *PTYOpener) SlaveName() string {
+	return o.slaveName
+}
+
+// Close releases the master side of the pseudo-terminal
+func (o *PTYOpener) Close() error {
+	return o.master.Close()
+}
+
+// ListPorts returns the slave side as the only port
+func (o *PTYOpener) ListPorts() ([]*enumerator.PortDetails, error) {
+	return []*enumerator.PortDetails{{Name: o.slaveName, Product: "pty"}}, nil
+}
+
+// Open opens the slave side in raw mode; the baud rate is ignored by ptys
+func (o *PTYOpener) Open(name string, cfg *config.SerialBridgeConfig) (Port, error) {
+	slave, err := os.OpenFile(name, os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := control(slave, makeRaw); err != nil {
+		slave.Close()
+		return nil, fmt.Errorf("failed to set raw mode on %s: %w", name, err)
+	}
+	return &connPort{conn: slave}, nil
+}
+
+// makeRaw disables line editing, echo and newline translation like cfmakeraw(3)
+func makeRaw(fd uintptr) error {
+	var t syscall.Termios
+	if err := ioctl(fd, syscall.TCGETS, uintptr(unsafe.Pointer(&t))); err != nil {
+		return err
+	}
+
+	t.Iflag &^= syscall.IGNBRK | syscall.BRKINT | syscall.PARMRK | syscall.ISTRIP | syscall.INLCR | syscall.IGNCR | syscall.ICRNL | syscall.IXON
+	t.Oflag &^= syscall.OPOST
+	t.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	t.Cflag &^= syscall.CSIZE | syscall.PARENB
+	t.Cflag |= syscall.CS8
+	t.Cc[syscall.VMIN] = 1
+	t.Cc[syscall.VTIME] = 0
+
+	return ioctl(fd, syscall.TCSETS, uintptr(unsafe.Pointer(&t)))
+}
+
+// control runs fn on the raw descriptor without switching the file to blocking mode
+func control(f *os.File, fn func(fd uintptr) error) error {
+	raw, err := f.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	var fnErr error
+	if err := raw.Control(func(fd uintptr) { fnErr = fn(fd) }); err != nil {
+		return err
+	}
+	return fnErr
+}
+
+func ioctl(fd, req, arg uintptr) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, req, arg); errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -4,40 +4,86 @@ import (
 	"bridge-serial/config"
 	"bridge-serial/pkg/logger"
 	"bufio"
+	"errors"
 	"fmt"
+	"io"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
-	"go.bug.st/serial"
 	"go.bug.st/serial/enumerator"
 )
 
+// ErrReadTimeout is returned by ReadData when no complete line arrived within the read timeout
+var ErrReadTimeout = errors.New("read timeout")
+
 type SerialBridge struct {
-	port     serial.Port
+	port     Port
 	portName string
 	reader   *bufio.Reader
+	pending  string
 
+	opener PortOpener
 	config *config.SerialBridgeConfig
+
+	// mu guards port, reader, portName, pending and opener against
+	// concurrent Write and Connect/Disconnect calls
+	mu sync.Mutex
+
+	bytesRead atomic.Uint64
+	linesRead atomic.Uint64
 }
 
-func NewSerialBridge(cfg *config.SerialBridgeConfig) *SerialBridge {
-	return &SerialBridge{config: cfg}
+// countingReader counts the bytes read from a port
+type countingReader struct {
+	r     io.Reader
+	count *atomic.Uint64
 }
 
-// Connect establishes connection to the serial port
-func (s *SerialBridge) Connect() error {
-	mode := &serial.Mode{
-		BaudRate: s.config.BaudRate,
-		DataBits: s.config.DataBits,
-		Parity:   s.config.Parity,
-		StopBits: s.config.StopBits,
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.count.Add(uint64(n))
+	return n, err
+}
+
+// NewSerialBridge creates a bridge that opens ports through the given opener,
+// falling back to the host serial ports when opener is nil
+func NewSerialBridge(cfg *config.SerialBridgeConfig, opener PortOpener) *SerialBridge {
+	if opener == nil {
+		opener = NewSystemOpener()
 	}
+	return &SerialBridge{config: cfg, opener: opener}
+}
 
-	err := s.getPortDevice()
+// SetOpener replaces the opener used by the next Connect, e.g. after the
+// transport changed. It must not be called while the port is being read.
+func (s *SerialBridge) SetOpener(opener PortOpener) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.opener = opener
+}
+
+// ListPorts returns the ports the opener can currently reach
+func (s *SerialBridge) ListPorts() ([]*enumerator.PortDetails, error) {
+	return s.currentOpener().ListPorts()
+}
+
+func (s *SerialBridge) currentOpener() PortOpener {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.opener
+}
+
+// Connect establishes connection to the serial port
+func (s *SerialBridge) Connect() error {
+	opener := s.currentOpener()
+	portName, err := s.getPortDevice(opener)
 	if err != nil {
-		return fmt.Errorf("failed to get port device: %v", err)
+		return fmt.Errorf("failed to get port device: %w", err)
 	}
 
-	port, err := serial.Open(s.portName, mode)
+	port, err := opener.Open(portName, s.config)
 	if err != nil {
 		return fmt.Errorf("failed to open serial port: %v", err)
 	}
@@ -49,14 +95,22 @@ func (s *SerialBridge) Connect() error {
 		return fmt.Errorf("failed to set read timeout: %v", err)
 	}
 
+	s.mu.Lock()
 	s.port = port
-	s.reader = bufio.NewReader(port)
-	logger.Info("connected to serial port: %s", s.portName)
+	s.portName = portName
+	s.reader = bufio.NewReader(&countingReader{r: port, count: &s.bytesRead})
+	s.pending = ""
+	s.mu.Unlock()
+	logger.Info("connected to serial port: %s", portName)
 	return nil
 }
 
-// Disconnect closes the serial port connection
+// Disconnect closes the serial port connection. It may be called while
+// another goroutine is blocked in ReadData, which then returns an error.
 func (s *SerialBridge) Disconnect() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	if s.port != nil {
 		err := s.port.Close()
 		s.port = nil
@@ -70,14 +124,30 @@ func (s *SerialBridge) Disconnect() error {
 
 // ReadData reads data from the serial port
 func (s *SerialBridge) ReadData() (string, error) {
-	if s.reader == nil {
+	s.mu.Lock()
+	reader := s.reader
+	s.mu.Unlock()
+
+	if reader == nil {
 		return "", fmt.Errorf("serial port not connected")
 	}
 	// Read until newline or timeout
-	data, err := s.reader.ReadString('\n')
+	data, err := reader.ReadString('\n')
+	if errors.Is(err, ErrReadTimeout) {
+		// Keep the partial line so it is completed by the next read
+		s.mu.Lock()
+		s.pending += data
+		s.mu.Unlock()
+		return "", ErrReadTimeout
+	}
 	if err != nil {
-		return "", fmt.Errorf("failed to read from serial port: %v", err)
+		return "", fmt.Errorf("failed to read from serial port: %w", err)
 	}
+	s.mu.Lock()
+	data = s.pending + data
+	s.pending = ""
+	s.mu.Unlock()
+	s.linesRead.Add(1)
 
 	// Clean the data (remove newlines and whitespace)
 	data = strings.TrimSpace(data)
@@ -85,28 +155,138 @@ func (s *SerialBridge) ReadData() (string, error) {
 	return data, nil
 }
 
-func (s *SerialBridge) getPortDevice() error {
-	ports, err := enumerator.GetDetailedPortsList()
+// Write sends raw bytes to the serial port
+func (s *SerialBridge) Write(data []byte) error {
+	s.mu.Lock()
+	port := s.port
+	s.mu.Unlock()
+
+	if port == nil {
+		return fmt.Errorf("serial port not connected")
+	}
+	if _, err := port.Write(data); err != nil {
+		return fmt.Errorf("failed to write to serial port: %w", err)
+	}
+	logger.Debug("wrote data to serial port: %q", data)
+	return nil
+}
+
+// SendCommand discards unread input, writes a request and waits up to
+// timeout for the response line accepted by match. Other lines, such as
+// continuous prints or the late reply to an earlier request, are skipped; a
+// nil match accepts any non-empty line. It reads from the port, so it must
+// be called from the same goroutine as ReadData.
+func (s *SerialBridge) SendCommand(cmd []byte, timeout time.Duration, match func(reply string) bool) (string, error) {
+	s.mu.Lock()
+	port := s.port
+	if port != nil {
+		s.reader.Discard(s.reader.Buffered())
+		s.pending = ""
+	}
+	s.mu.Unlock()
+	if port == nil {
+		return "", fmt.Errorf("serial port not connected")
+	}
+	if err := port.ResetInputBuffer(); err != nil {
+		return "", fmt.Errorf("failed to discard stale input: %w", err)
+	}
+	defer port.SetReadTimeout(s.config.Timeout)
+
+	if err := s.Write(cmd); err != nil {
+		return "", err
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return "", fmt.Errorf("no response to %q within %s: %w", cmd, timeout, ErrReadTimeout)
+		}
+		if err := port.SetReadTimeout(remaining); err != nil {
+			return "", fmt.Errorf("failed to set read timeout: %v", err)
+		}
+
+		data, err := s.ReadData()
+		if errors.Is(err, ErrReadTimeout) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if data == "" {
+			continue
+		}
+		if match != nil && !match(data) {
+			logger.Debug("skipping line that does not answer %q: %s", cmd, data)
+			continue
+		}
+		return data, nil
+	}
+}
+
+// getPortDevice returns the name of the port to open through opener
+func (s *SerialBridge) getPortDevice(opener PortOpener) (string, error) {
+	if direct, ok := opener.(directOpener); ok {
+		name := direct.directPort()
+		if name == "" {
+			return "", fmt.Errorf("%w: no device server address configured", ErrNoMatchingDevice)
+		}
+		logger.Info("selected serial port: %s", name)
+		return name, nil
+	}
+
+	ports, err := opener.ListPorts()
 	if err != nil {
 		logger.Error("failed to enumerate ports: %v", err)
-		return fmt.Errorf("failed to enumerate ports: %v", err)
+		return "", fmt.Errorf("failed to enumerate ports: %v", err)
+	}
+
+	port, err := matchPort(s.config.Devices, ports)
+	if err != nil {
+		logger.Error("failed to find serial device: %v", err)
+		return "", err
+	}
+
+	logger.Info("selected serial port: %s", describePort(port))
+	return port.Name, nil
+}
+
+// IsDevicePresent reports whether the current port is still enumerated by the OS
+func (s *SerialBridge) IsDevicePresent() (bool, error) {
+	ports, err := s.ListPorts()
+	if err != nil {
+		return false, fmt.Errorf("failed to enumerate ports: %w", err)
 	}
 
+	portName := s.GetPortName()
 	for _, port := range ports {
-		if port.IsUSB && port.VID == "067B" && port.PID == "2303" {
-			s.portName = port.Name
-			break
+		if port.Name == portName {
+			return true, nil
 		}
 	}
-	return nil
+	return false, nil
 }
 
 // IsConnected returns true if the serial port is connected
 func (s *SerialBridge) IsConnected() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	return s.port != nil
 }
 
+// BytesRead returns the number of bytes read from serial ports so far
+func (s *SerialBridge) BytesRead() uint64 {
+	return s.bytesRead.Load()
+}
+
+// LinesRead returns the number of complete lines read from serial ports so far
+func (s *SerialBridge) LinesRead() uint64 {
+	return s.linesRead.Load()
+}
+
 // GetPortName returns the current port name
 func (s *SerialBridge) GetPortName() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	return s.portName
 }
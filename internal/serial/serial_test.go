@@ -0,0 +1,136 @@
+package serial
+
+import (
+	"bridge-serial/config"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConnectOverTCPWithDefaultRules(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	server, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		conn, err := server.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.Write([]byte("WTST   12.11   g\r\n"))
+		<-done
+	}()
+
+	loaded, err := config.Load(config.LoadOptions{Mode: "dev"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := loaded.Config.SerialBridge
+	cfg.Transport = "tcp"
+	cfg.TCPAddress = server.Addr().String()
+	if len(cfg.Devices) == 0 || cfg.Devices[0].VID == "" {
+		t.Fatalf("default rules %+v are not USB rules", cfg.Devices)
+	}
+
+	opener, err := NewPortOpener(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bridge := NewSerialBridge(&cfg, opener)
+	if err := bridge.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer bridge.Disconnect()
+
+	if name := bridge.GetPortName(); name != cfg.TCPAddress {
+		t.Fatalf("port = %s, want %s", name, cfg.TCPAddress)
+	}
+	data, err := bridge.ReadData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data != "WTST   12.11   g" {
+		t.Fatalf("ReadData = %q", data)
+	}
+	if present, err := bridge.IsDevicePresent(); err != nil || !present {
+		t.Fatalf("IsDevicePresent = %v, %v", present, err)
+	}
+}
+
+func TestPortStateAcrossGoroutines(t *testing.T) {
+	cfg := config.SerialBridgeConfig{
+		Devices: []config.DeviceMatchRule{{PortName: "pipe"}},
+		Timeout: 100 * time.Millisecond,
+	}
+	bridge := NewSerialBridge(&cfg, NewPipeOpener("pipe"))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 50; i++ {
+			bridge.SetOpener(NewPipeOpener("pipe"))
+			if err := bridge.Connect(); err != nil {
+				t.Error(err)
+				return
+			}
+			bridge.Disconnect()
+		}
+	}()
+
+	for {
+		select {
+		case <-done:
+			return
+		default:
+			bridge.GetPortName()
+			bridge.IsDevicePresent()
+		}
+	}
+}
+
+func TestSendCommandSkipsStaleInput(t *testing.T) {
+	cfg := config.SerialBridgeConfig{
+		Devices: []config.DeviceMatchRule{{PortName: "pipe"}},
+		Timeout: time.Second,
+	}
+	pipe := NewPipeOpener("pipe")
+	bridge := NewSerialBridge(&cfg, pipe)
+	if err := bridge.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer bridge.Disconnect()
+
+	device := pipe.Accept()
+	defer device.Close()
+	go func() {
+		// a reply to an earlier request that timed out, still unread
+		device.Write([]byte("SI S      1.00 g\r\n"))
+
+		command := make([]byte, 16)
+		n, err := device.Read(command)
+		if err != nil || string(command[:n]) != "SI\r\n" {
+			t.Errorf("device received %q, %v", command[:n], err)
+			return
+		}
+		device.Write([]byte("Z A\r\nSI S      2.00 g\r\n"))
+	}()
+
+	// the stale reply is written before the command is sent
+	time.Sleep(20 * time.Millisecond)
+	reply, err := bridge.SendCommand([]byte("SI\r\n"), time.Second, func(reply string) bool {
+		return strings.HasPrefix(reply, "SI ")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply != "SI S      2.00 g" {
+		t.Fatalf("SendCommand = %q, want the reply to this request", reply)
+	}
+}
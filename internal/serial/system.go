@@ -0,0 +1,59 @@
+package serial
+
+import (
+	"bridge-serial/config"
+	"time"
+
+	"go.bug.st/serial"
+	"go.bug.st/serial/enumerator"
+)
+
+// SystemOpener opens the serial ports of the host OS
+type SystemOpener struct{}
+
+// NewSystemOpener creates an opener for the host serial ports
+func NewSystemOpener() *SystemOpener {
+	return &SystemOpener{}
+}
+
+// ListPorts enumerates the host serial ports with their USB details
+func (o *SystemOpener) ListPorts() ([]*enumerator.PortDetails, error) {
+	return enumerator.GetDetailedPortsList()
+}
+
+// Open opens a host serial port with the configured mode
+func (o *SystemOpener) Open(name string, cfg *config.SerialBridgeConfig) (Port, error) {
+	mode := &serial.Mode{
+		BaudRate: cfg.BaudRate,
+		DataBits: cfg.DataBits,
+		Parity:   cfg.Parity,
+		StopBits: cfg.StopBits,
+	}
+
+	port, err := serial.Open(name, mode)
+	if err != nil {
+		return nil, err
+	}
+	return &systemPort{Port: port}, nil
+}
+
+// systemPort turns the (0, nil) result the serial driver returns on a
+// read timeout into ErrReadTimeout, so bufio does not spin on empty reads.
+type systemPort struct {
+	serial.Port
+}
+
+func (p *systemPort) Read(b []byte) (int, error) {
+	n, err := p.Port.Read(b)
+	if n == 0 && err == nil {
+		return 0, ErrReadTimeout
+	}
+	return n, err
+}
+
+func (p *systemPort) SetReadTimeout(t time.Duration) error {
+	if t <= 0 {
+		t = serial.NoTimeout
+	}
+	return p.Port.SetReadTimeout(t)
+}
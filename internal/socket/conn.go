@@ -0,0 +1,153 @@
+package socket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// ClientConn is a Go client for the bridge WebSocket endpoint. Replies are
+// matched to requests by id; every other message is delivered on Messages.
+type ClientConn struct {
+	conn     *websocket.Conn
+	messages chan Message
+	done     chan struct{}
+	nextID   atomic.Uint64
+
+	writeMu sync.Mutex
+	mu      sync.Mutex
+	pending map[string]chan Message
+	err     error
+}
+
+// Dial connects to a bridge WebSocket endpoint such as ws://localhost:8001/ws
+func Dial(ctx context.Context, url string, header http.Header) (*ClientConn, error) {
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, url, header)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial %s: %w", url, err)
+	}
+
+	c := &ClientConn{
+		conn:     conn,
+		messages: make(chan Message, 256),
+		done:     make(chan struct{}),
+		pending:  make(map[string]chan Message),
+	}
+	go c.readLoop()
+	return c, nil
+}
+
+// Messages returns the messages that are not replies to a Call, such as
+// scale_data broadcasts. Messages are dropped when the channel is full.
+func (c *ClientConn) Messages() <-chan Message {
+	return c.messages
+}
+
+// Call sends a request and waits for the reply with the matching reply_to.
+// An error reply is returned together with its *Error.
+func (c *ClientConn) Call(ctx context.Context, msgType string, payload interface{}) (*Message, error) {
+	id := fmt.Sprintf("req_%d", c.nextID.Add(1))
+	replyChan := make(chan Message, 1)
+
+	c.mu.Lock()
+	if c.err != nil {
+		err := c.err
+		c.mu.Unlock()
+		return nil, err
+	}
+	c.pending[id] = replyChan
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	if err := c.Send(Message{ID: id, Type: msgType, Payload: payload}); err != nil {
+		return nil, err
+	}
+
+	select {
+	case reply := <-replyChan:
+		if reply.Error != nil {
+			return &reply, reply.Error
+		}
+		return &reply, nil
+	case <-c.done:
+		return nil, c.closeErr()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Send writes a message without waiting for a reply
+func (c *ClientConn) Send(msg Message) error {
+	if msg.Ts == 0 {
+		msg.Ts = time.Now().UnixMilli()
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	return c.conn.WriteJSON(msg)
+}
+
+// Close closes the connection and fails pending calls
+func (c *ClientConn) Close() error {
+	c.writeMu.Lock()
+	c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	c.writeMu.Unlock()
+	return c.conn.Close()
+}
+
+// readLoop dispatches incoming messages until the connection fails
+func (c *ClientConn) readLoop() {
+	defer close(c.done)
+	defer close(c.messages)
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			c.mu.Lock()
+			c.err = fmt.Errorf("connection closed: %w", err)
+			c.mu.Unlock()
+			return
+		}
+
+		var msg Message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			continue
+		}
+
+		if msg.ReplyTo != "" {
+			c.mu.Lock()
+			replyChan, ok := c.pending[msg.ReplyTo]
+			c.mu.Unlock()
+			if ok {
+				select {
+				case replyChan <- msg:
+				default:
+				}
+				continue
+			}
+		}
+
+		select {
+		case c.messages <- msg:
+		default:
+		}
+	}
+}
+
+func (c *ClientConn) closeErr() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
@@ -0,0 +1,145 @@
+package socket
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// dialTest connects a ClientConn to handler served by httptest
+func dialTest(t *testing.T, handler http.Handler) *ClientConn {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := Dial(ctx, "ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestCallMatchesRepliesByID(t *testing.T) {
+	// answers two requests in reverse order with a broadcast in between
+	conn := dialTest(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+
+		var requests [2]Message
+		for i := range requests {
+			if err := ws.ReadJSON(&requests[i]); err != nil {
+				return
+			}
+		}
+		ws.WriteJSON(Message{ReplyTo: requests[1].ID, Type: "ack", Payload: requests[1].Payload})
+		ws.WriteJSON(Message{Type: "scale_data", Payload: "broadcast"})
+		ws.WriteJSON(Message{ReplyTo: requests[0].ID, Type: "ack", Payload: requests[0].Payload})
+		ws.ReadMessage()
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	type result struct {
+		reply *Message
+		err   error
+	}
+	first := make(chan result, 1)
+	go func() {
+		reply, err := conn.Call(ctx, "echo", "first")
+		first <- result{reply, err}
+	}()
+	// make sure the first request is written before the second
+	time.Sleep(50 * time.Millisecond)
+
+	reply, err := conn.Call(ctx, "echo", "second")
+	if err != nil || reply.Payload != "second" {
+		t.Fatalf("second Call = %+v, %v", reply, err)
+	}
+	got := <-first
+	if got.err != nil || got.reply.Payload != "first" {
+		t.Fatalf("first Call = %+v, %v", got.reply, got.err)
+	}
+
+	select {
+	case msg := <-conn.Messages():
+		if msg.Type != "scale_data" || msg.ReplyTo != "" {
+			t.Fatalf("Messages got %+v, want the broadcast", msg)
+		}
+	case <-ctx.Done():
+		t.Fatal("broadcast was not delivered on Messages")
+	}
+}
+
+func TestCallErrorReply(t *testing.T) {
+	s := NewServer()
+	s.Start()
+	defer s.Stop()
+	s.Handle("fail", func(client *Client, payload interface{}) (interface{}, error) {
+		return nil, errors.New("scale is busy")
+	})
+	conn := dialTest(t, http.HandlerFunc(s.ServeWS))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tests := []struct {
+		msgType string
+		code    string
+		message string
+	}{
+		{"fail", ErrCodeRequestFailed, "scale is busy"},
+		{"missing", ErrCodeUnknownType, "unknown message type 'missing'"},
+	}
+	for _, tt := range tests {
+		reply, err := conn.Call(ctx, tt.msgType, nil)
+		var replyErr *Error
+		if !errors.As(err, &replyErr) {
+			t.Fatalf("Call(%s) error = %v, want *Error", tt.msgType, err)
+		}
+		if replyErr.Code != tt.code || replyErr.Message != tt.message {
+			t.Errorf("Call(%s) error = %+v, want %s: %s", tt.msgType, replyErr, tt.code, tt.message)
+		}
+		if reply == nil || reply.Type != "error" {
+			t.Errorf("Call(%s) reply = %+v, want the error envelope", tt.msgType, reply)
+		}
+	}
+}
+
+func TestCallContextCanceled(t *testing.T) {
+	s := NewServer()
+	s.Start()
+	defer s.Stop()
+	release := make(chan struct{})
+	defer close(release)
+	s.Handle("slow", func(client *Client, payload interface{}) (interface{}, error) {
+		<-release
+		return "done", nil
+	})
+	conn := dialTest(t, http.HandlerFunc(s.ServeWS))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := conn.Call(ctx, "slow", nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Call error = %v, want context.DeadlineExceeded", err)
+	}
+
+	conn.mu.Lock()
+	pending := len(conn.pending)
+	conn.mu.Unlock()
+	if pending != 0 {
+		t.Fatalf("%d calls still pending after the context ended", pending)
+	}
+}
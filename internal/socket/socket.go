@@ -1,21 +1,46 @@
 package socket
 
 import (
+	"bridge-serial/internal/auth"
 	"bridge-serial/pkg/logger"
 	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
 )
 
-// Message represents the websocket message format matching the client
+// Message represents the websocket message format matching the client.
+// ID is set by the sender of a request and echoed in ReplyTo of the reply.
 type Message struct {
+	ID      string      `json:"id,omitempty"`
+	ReplyTo string      `json:"reply_to,omitempty"`
+	Topic   string      `json:"topic,omitempty"`
 	Type    string      `json:"type"`
 	Payload interface{} `json:"payload,omitempty"`
+	Error   *Error      `json:"error,omitempty"`
+	Ts      int64       `json:"ts,omitempty"`
+}
+
+// Error codes sent in error replies
+const (
+	ErrCodeMalformed     = "malformed_message"
+	ErrCodeUnknownType   = "unknown_type"
+	ErrCodeRequestFailed = "request_failed"
+)
+
+// Error describes why a request failed
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
 }
 
 // Client represents a connected websocket client
@@ -25,12 +50,27 @@ type Client struct {
 	send     chan Message
 	server   *Server
 	lastPong time.Time
+	topics   map[string]bool // nil until the client first subscribes
 	mu       sync.RWMutex
+
+	// sendMu orders sends on send with closing it, which happens when the
+	// client disconnects or is evicted while a request is still being handled
+	sendMu     sync.Mutex
+	sendClosed bool
 }
 
+// HandlerFunc handles a client message type registered with Server.Handle.
+// The returned result is sent back to the client in an "ack" reply and a
+// returned error in an "error" reply, both carrying the request_id of the request.
+type HandlerFunc func(client *Client, payload interface{}) (interface{}, error)
+
 // Server represents the websocket server
 type Server struct {
 	clients    map[*Client]bool
+	handlers   map[string]HandlerFunc
+	onConnect  func(client *Client)
+	onPublish  func(message Message)
+	dropped    atomic.Uint64
 	broadcast  chan Message
 	register   chan *Client
 	unregister chan *Client
@@ -40,18 +80,25 @@ type Server struct {
 	cancel     context.CancelFunc
 }
 
+// broadcastBuffer is how many messages may wait for the broadcast loop
+// before BroadcastMessage and PublishTopic drop them
+const broadcastBuffer = 256
+
 // NewServer creates a new websocket server
 func NewServer() *Server {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	return &Server{
 		clients:    make(map[*Client]bool),
-		broadcast:  make(chan Message),
+		handlers:   make(map[string]HandlerFunc),
+		broadcast:  make(chan Message, broadcastBuffer),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
 		upgrader: websocket.Upgrader{
 			ReadBufferSize:  1024,
 			WriteBufferSize: 1024,
+			// Echo the marker browsers send ahead of the token, see auth.SubprotocolBearer
+			Subprotocols: []string{auth.SubprotocolBearer},
 			CheckOrigin: func(r *http.Request) bool {
 				// Allow connections from any origin for development
 				// In production, you should implement proper origin checking
@@ -63,6 +110,12 @@ func NewServer() *Server {
 	}
 }
 
+// SetOriginChecker replaces the origin check applied to WebSocket upgrades.
+// It must be called before the server starts accepting connections.
+func (s *Server) SetOriginChecker(check func(r *http.Request) bool) {
+	s.upgrader.CheckOrigin = check
+}
+
 // Start starts the websocket server
 func (s *Server) Start() {
 	// Reset the server state for fresh start
@@ -96,7 +149,7 @@ func (s *Server) resetServer() {
 				// Drain the channel
 			default:
 			}
-			close(client.send)
+			client.closeSend()
 		}
 		delete(s.clients, client)
 	}
@@ -105,7 +158,7 @@ func (s *Server) resetServer() {
 	s.clients = make(map[*Client]bool)
 
 	// Recreate channels
-	s.broadcast = make(chan Message)
+	s.broadcast = make(chan Message, broadcastBuffer)
 	s.register = make(chan *Client)
 	s.unregister = make(chan *Client)
 
@@ -131,7 +184,7 @@ func (s *Server) Stop() {
 			client.conn.Close()
 		}
 		if client.send != nil {
-			close(client.send)
+			client.closeSend()
 		}
 		delete(s.clients, client)
 	}
@@ -140,6 +193,20 @@ func (s *Server) Stop() {
 	logger.Info("WebSocket server stopped")
 }
 
+// CloseClients disconnects every client while the server keeps running, so
+// they reconnect with the current address and credentials. Only the
+// connections are closed: each readPump then unregisters its client, which
+// closes the send channel once no request of that client is being handled.
+func (s *Server) CloseClients() {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for client := range s.clients {
+		if client.conn != nil {
+			client.conn.Close()
+		}
+	}
+}
+
 // handleConnections manages client connections and message broadcasting
 func (s *Server) handleConnections() {
 	for {
@@ -150,29 +217,49 @@ func (s *Server) handleConnections() {
 		case client := <-s.register:
 			s.mu.Lock()
 			s.clients[client] = true
+			total := len(s.clients)
+			onConnect := s.onConnect
 			s.mu.Unlock()
-			logger.Info("Client %s connected. Total clients: %d", client.id, len(s.clients))
+			logger.Info("Client %s connected. Total clients: %d", client.id, total)
+			if onConnect != nil {
+				onConnect(client)
+			}
 
 		case client := <-s.unregister:
 			s.mu.Lock()
 			if _, ok := s.clients[client]; ok {
 				delete(s.clients, client)
-				close(client.send)
+				client.closeSend()
 				logger.Info("Client %s disconnected. Total clients: %d", client.id, len(s.clients))
 			}
 			s.mu.Unlock()
 
 		case message := <-s.broadcast:
+			var slow []*Client
 			s.mu.RLock()
 			for client := range s.clients {
-				select {
-				case client.send <- message:
-				default:
-					close(client.send)
-					delete(s.clients, client)
+				if message.Topic != "" && !client.acceptsTopic(message.Topic) {
+					continue
+				}
+				if !client.queue(message) {
+					s.dropped.Add(1)
+					slow = append(slow, client)
 				}
 			}
 			s.mu.RUnlock()
+
+			// evict clients that cannot keep up, under the write lock
+			if len(slow) > 0 {
+				s.mu.Lock()
+				for _, client := range slow {
+					if _, ok := s.clients[client]; ok {
+						delete(s.clients, client)
+						client.closeSend()
+						logger.Warn("Client %s evicted, its send channel is full", client.id)
+					}
+				}
+				s.mu.Unlock()
+			}
 		}
 	}
 }
@@ -209,14 +296,74 @@ func (s *Server) BroadcastMessage(msgType string, payload interface{}) {
 		Type:    msgType,
 		Payload: payload,
 	}
+	s.notifyPublish(message)
 
 	select {
 	case s.broadcast <- message:
 	default:
+		s.dropped.Add(1)
 		logger.Error("Broadcast channel full, message dropped")
 	}
 }
 
+// PublishTopic sends a message to the clients subscribed to a matching topic
+func (s *Server) PublishTopic(topic, msgType string, payload interface{}) {
+	message := Message{
+		Topic:   topic,
+		Type:    msgType,
+		Payload: payload,
+	}
+	s.notifyPublish(message)
+
+	select {
+	case s.broadcast <- message:
+	default:
+		s.dropped.Add(1)
+		logger.Error("Broadcast channel full, message on topic %s dropped", topic)
+	}
+}
+
+// Handle registers a handler for a client message type
+func (s *Server) Handle(msgType string, handler HandlerFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[msgType] = handler
+}
+
+// OnConnect registers a callback run for each newly registered client,
+// before any broadcast reaches it
+func (s *Server) OnConnect(fn func(client *Client)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onConnect = fn
+}
+
+// OnPublish registers a callback receiving every broadcast and published
+// message, whether or not a client is connected, to mirror them elsewhere
+func (s *Server) OnPublish(fn func(message Message)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onPublish = fn
+}
+
+// notifyPublish passes a message to the OnPublish callback, stamping its time
+func (s *Server) notifyPublish(message Message) {
+	s.mu.RLock()
+	onPublish := s.onPublish
+	s.mu.RUnlock()
+
+	if onPublish != nil {
+		message.Ts = time.Now().UnixMilli()
+		onPublish(message)
+	}
+}
+
+// DroppedMessages returns how many messages were discarded because the
+// broadcast channel or a client's send channel was full
+func (s *Server) DroppedMessages() uint64 {
+	return s.dropped.Load()
+}
+
 // GetConnectedClientsCount returns the number of connected clients
 func (s *Server) GetConnectedClientsCount() int {
 	s.mu.RLock()
@@ -254,6 +401,7 @@ func (c *Client) readPump() {
 		var msg Message
 		if err := json.Unmarshal(messageData, &msg); err != nil {
 			logger.Error("Failed to unmarshal message from client %s: %v", c.id, err)
+			c.sendError(Message{}, ErrCodeMalformed, fmt.Sprintf("invalid message: %v", err))
 			continue
 		}
 
@@ -279,6 +427,10 @@ func (c *Client) writePump() {
 				return
 			}
 
+			if message.Ts == 0 {
+				message.Ts = time.Now().UnixMilli()
+			}
+
 			jsonData, err := json.Marshal(message)
 			if err != nil {
 				logger.Error("Failed to marshal message for client %s: %v", c.id, err)
@@ -307,13 +459,14 @@ func (c *Client) handleMessage(msg Message) {
 	case "ping":
 		// Respond with pong
 		response := Message{
+			ReplyTo: msg.ID,
 			Type:    "pong",
 			Payload: msg.Payload,
 		}
-		select {
-		case c.send <- response:
+		if c.queue(response) {
 			logger.Info("Sent pong response to client %s", c.id)
-		default:
+		} else {
+			c.server.dropped.Add(1)
 			logger.Error("Failed to send pong response to client %s", c.id)
 		}
 
@@ -323,13 +476,14 @@ func (c *Client) handleMessage(msg Message) {
 
 		// Auto-respond with sync-from-self message
 		response := Message{
+			ReplyTo: msg.ID,
 			Type:    "sync-from-self",
 			Payload: "pong",
 		}
-		select {
-		case c.send <- response:
+		if c.queue(response) {
 			logger.Info("Sent sync-from-self response to client %s", c.id)
-		default:
+		} else {
+			c.server.dropped.Add(1)
 			logger.Error("Failed to send sync-from-self response to client %s", c.id)
 		}
 
@@ -337,11 +491,126 @@ func (c *Client) handleMessage(msg Message) {
 		// Handle sync-from-self messages (informational)
 		logger.Info("Received sync-from-self from client %s with payload: %v", c.id, msg.Payload)
 
+	case "subscribe":
+		c.handleRequest(msg, func(client *Client, payload interface{}) (interface{}, error) {
+			patterns, err := topicsFromPayload(payload)
+			if err != nil {
+				return nil, err
+			}
+			client.Subscribe(patterns...)
+			return map[string]interface{}{"topics": client.Subscriptions()}, nil
+		})
+
+	case "unsubscribe":
+		c.handleRequest(msg, func(client *Client, payload interface{}) (interface{}, error) {
+			patterns, err := topicsFromPayload(payload)
+			if err != nil {
+				return nil, err
+			}
+			client.Unsubscribe(patterns...)
+			return map[string]interface{}{"topics": client.Subscriptions()}, nil
+		})
+
+	default:
+		c.server.mu.RLock()
+		handler, ok := c.server.handlers[msg.Type]
+		c.server.mu.RUnlock()
+		if !ok {
+			logger.Info("Received unknown message type '%s' from client %s with payload: %v", msg.Type, c.id, msg.Payload)
+			c.sendError(msg, ErrCodeUnknownType, fmt.Sprintf("unknown message type '%s'", msg.Type))
+			return
+		}
+		c.handleRequest(msg, handler)
+	}
+}
+
+// handleRequest runs a registered handler and replies with an ack or error.
+// The request is identified by the envelope id, or by payload.request_id for
+// clients that predate the envelope fields.
+func (c *Client) handleRequest(msg Message, handler HandlerFunc) {
+	requestID := msg.ID
+	if payload, ok := msg.Payload.(map[string]interface{}); ok && requestID == "" {
+		requestID, _ = payload["request_id"].(string)
+	}
+
+	result, err := handler(c, msg.Payload)
+	if err != nil {
+		logger.Error("Request %s '%s' from client %s failed: %v", requestID, msg.Type, c.id, err)
+		c.sendReply(Message{
+			ReplyTo: msg.ID,
+			Type:    "error",
+			Payload: map[string]interface{}{
+				"request_id": requestID,
+				"type":       msg.Type,
+				"error":      err.Error(),
+			},
+			Error: &Error{Code: ErrCodeRequestFailed, Message: err.Error()},
+		})
+		return
+	}
+
+	logger.Info("Request %s '%s' from client %s succeeded", requestID, msg.Type, c.id)
+	c.sendReply(Message{
+		ReplyTo: msg.ID,
+		Type:    "ack",
+		Payload: map[string]interface{}{
+			"request_id": requestID,
+			"type":       msg.Type,
+			"result":     result,
+		},
+	})
+}
+
+// sendError replies to a request with an error envelope
+func (c *Client) sendError(request Message, code, message string) {
+	c.sendReply(Message{
+		ReplyTo: request.ID,
+		Type:    "error",
+		Error:   &Error{Code: code, Message: message},
+	})
+}
+
+// sendReply queues a reply for this client, dropping it if the send channel is full
+func (c *Client) sendReply(reply Message) {
+	if !c.queue(reply) {
+		c.server.dropped.Add(1)
+		logger.Error("Client %s send channel full or closed, reply '%s' dropped", c.id, reply.Type)
+	}
+}
+
+// queue hands a message to writePump without blocking. It reports false
+// when the send channel is full or already closed.
+func (c *Client) queue(message Message) bool {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+
+	if c.sendClosed {
+		return false
+	}
+	select {
+	case c.send <- message:
+		return true
 	default:
-		logger.Info("Received unknown message type '%s' from client %s with payload: %v", msg.Type, c.id, msg.Payload)
+		return false
+	}
+}
+
+// closeSend closes the send channel once, which makes writePump close the connection
+func (c *Client) closeSend() {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+
+	if !c.sendClosed {
+		c.sendClosed = true
+		close(c.send)
 	}
 }
 
+// ID returns the client identifier
+func (c *Client) ID() string {
+	return c.id
+}
+
 // SendMessage sends a message to this specific client
 func (c *Client) SendMessage(msgType string, payload interface{}) {
 	message := Message{
@@ -349,10 +618,9 @@ func (c *Client) SendMessage(msgType string, payload interface{}) {
 		Payload: payload,
 	}
 
-	select {
-	case c.send <- message:
-	default:
-		logger.Error("Client %s send channel full, message dropped", c.id)
+	if !c.queue(message) {
+		c.server.dropped.Add(1)
+		logger.Error("Client %s send channel full or closed, message dropped", c.id)
 	}
 }
 
@@ -0,0 +1,116 @@
+package socket
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCloseClientsDuringRequest(t *testing.T) {
+	s := NewServer()
+	s.Start()
+	defer s.Stop()
+
+	inHandler := make(chan struct{})
+	release := make(chan struct{})
+	s.Handle("slow", func(client *Client, payload interface{}) (interface{}, error) {
+		close(inHandler)
+		<-release
+		return "done", nil
+	})
+
+	server := httptest.NewServer(http.HandlerFunc(s.ServeWS))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := Dial(ctx, "ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	go conn.Call(ctx, "slow", nil)
+	select {
+	case <-inHandler:
+	case <-ctx.Done():
+		t.Fatal("request did not reach the handler")
+	}
+
+	// the reply is sent after the client was disconnected, which must not panic
+	s.CloseClients()
+	close(release)
+
+	// readPump unregisters the client once the handler has replied
+	for s.GetConnectedClientsCount() != 0 {
+		select {
+		case <-ctx.Done():
+			t.Fatalf("%d clients still registered", s.GetConnectedClientsCount())
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+// waitEvicted broadcasts until the server has dropped every client
+func waitEvicted(t *testing.T, s *Server) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for s.GetConnectedClientsCount() != 0 {
+		s.BroadcastMessage("scale_data", nil)
+		select {
+		case <-ctx.Done():
+			t.Fatal("slow client was not evicted")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func TestEvictSlowClient(t *testing.T) {
+	s := NewServer()
+	s.Start()
+	defer s.Stop()
+
+	// nothing drains send, so the second broadcast overflows it
+	client := &Client{id: "slow", send: make(chan Message, 1), server: s}
+	s.register <- client
+	waitEvicted(t, s)
+
+	if _, ok := <-client.send; !ok {
+		t.Fatal("queued broadcast was lost")
+	}
+	if _, ok := <-client.send; ok {
+		t.Fatal("send channel of the evicted client is still open")
+	}
+}
+
+func TestEvictSlowClientDuringRequest(t *testing.T) {
+	s := NewServer()
+	s.Start()
+	defer s.Stop()
+
+	client := &Client{id: "slow", send: make(chan Message, 1), server: s}
+	s.register <- client
+
+	inHandler := make(chan struct{})
+	release := make(chan struct{})
+	replied := make(chan struct{})
+	go func() {
+		defer close(replied)
+		client.handleRequest(Message{ID: "req_1", Type: "slow"}, func(client *Client, payload interface{}) (interface{}, error) {
+			close(inHandler)
+			<-release
+			return "done", nil
+		})
+	}()
+	<-inHandler
+	waitEvicted(t, s)
+
+	// the reply goes to the evicted client, which must not panic
+	close(release)
+	<-replied
+	client.SendMessage("status", nil)
+}
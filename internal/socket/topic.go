@@ -0,0 +1,109 @@
+package socket
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// MatchTopic reports whether a topic such as "scale/COM3" matches a
+// subscription pattern. Topics are split on "/"; "*" matches exactly one
+// segment and a trailing "#" matches any number of remaining segments.
+func MatchTopic(pattern, topic string) bool {
+	patternParts := strings.Split(pattern, "/")
+	topicParts := strings.Split(topic, "/")
+
+	for i, part := range patternParts {
+		if part == "#" && i == len(patternParts)-1 {
+			return true
+		}
+		if i >= len(topicParts) {
+			return false
+		}
+		if part != "*" && part != topicParts[i] {
+			return false
+		}
+	}
+	return len(patternParts) == len(topicParts)
+}
+
+// Subscribe adds topic patterns to the client's subscriptions
+func (c *Client) Subscribe(patterns ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.topics == nil {
+		c.topics = make(map[string]bool)
+	}
+	for _, pattern := range patterns {
+		c.topics[pattern] = true
+	}
+}
+
+// Unsubscribe removes topic patterns from the client's subscriptions
+func (c *Client) Unsubscribe(patterns ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, pattern := range patterns {
+		delete(c.topics, pattern)
+	}
+}
+
+// Subscriptions returns the client's topic patterns in sorted order
+func (c *Client) Subscriptions() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	patterns := make([]string, 0, len(c.topics))
+	for pattern := range c.topics {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+	return patterns
+}
+
+// acceptsTopic reports whether a message published on topic should be sent to
+// the client. Clients that never subscribed receive every topic, as they did
+// before subscriptions existed.
+func (c *Client) acceptsTopic(topic string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.topics == nil {
+		return true
+	}
+	for pattern := range c.topics {
+		if MatchTopic(pattern, topic) {
+			return true
+		}
+	}
+	return false
+}
+
+// topicsFromPayload reads the patterns of a subscribe or unsubscribe request,
+// given either as payload.topic or payload.topics
+func topicsFromPayload(payload interface{}) ([]string, error) {
+	fields, ok := payload.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("payload must be an object with topic or topics")
+	}
+
+	var patterns []string
+	if topic, ok := fields["topic"].(string); ok && topic != "" {
+		patterns = append(patterns, topic)
+	}
+	if topics, ok := fields["topics"].([]interface{}); ok {
+		for _, topic := range topics {
+			pattern, ok := topic.(string)
+			if !ok || pattern == "" {
+				return nil, fmt.Errorf("topics must be non-empty strings")
+			}
+			patterns = append(patterns, pattern)
+		}
+	}
+	if len(patterns) == 0 {
+		return nil, fmt.Errorf("payload must be an object with topic or topics")
+	}
+	return patterns, nil
+}
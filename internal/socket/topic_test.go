@@ -0,0 +1,93 @@
+package socket
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMatchTopic(t *testing.T) {
+	tests := []struct {
+		pattern string
+		topic   string
+		want    bool
+	}{
+		{"scale/ttyUSB0", "scale/ttyUSB0", true},
+		{"scale/ttyUSB0", "scale/ttyUSB1", false},
+		{"scale/*", "scale/COM3", true},
+		{"scale/*", "scale", false},
+		{"scale/*", "scale/COM3/raw", false},
+		{"*/ttyUSB0", "scale/ttyUSB0", true},
+		{"scale/#", "scale/COM3", true},
+		{"scale/#", "scale/COM3/raw", true},
+		{"scale/#", "scale", true},
+		{"scale/#", "status", false},
+		{"#", "status", true},
+		{"scale/#/raw", "scale/COM3/raw", false},
+		{"status", "status/serial", false},
+		{"status/serial", "status", false},
+	}
+	for _, tt := range tests {
+		if got := MatchTopic(tt.pattern, tt.topic); got != tt.want {
+			t.Errorf("MatchTopic(%q, %q) = %v, want %v", tt.pattern, tt.topic, got, tt.want)
+		}
+	}
+}
+
+func TestAcceptsTopic(t *testing.T) {
+	client := &Client{}
+	for _, topic := range []string{"scale/ttyUSB0", "status", "anything/at/all"} {
+		if !client.acceptsTopic(topic) {
+			t.Errorf("client that never subscribed rejected %q", topic)
+		}
+	}
+
+	client.Subscribe("scale/*", "status")
+	tests := []struct {
+		topic string
+		want  bool
+	}{
+		{"scale/ttyUSB0", true},
+		{"status", true},
+		{"config", false},
+	}
+	for _, tt := range tests {
+		if got := client.acceptsTopic(tt.topic); got != tt.want {
+			t.Errorf("acceptsTopic(%q) = %v, want %v", tt.topic, got, tt.want)
+		}
+	}
+	if got := client.Subscriptions(); !reflect.DeepEqual(got, []string{"scale/*", "status"}) {
+		t.Errorf("Subscriptions = %v", got)
+	}
+
+	// unsubscribing from everything does not restore the receive-all default
+	client.Unsubscribe("scale/*", "status")
+	if client.acceptsTopic("status") {
+		t.Error("client without subscriptions left still receives topics")
+	}
+}
+
+func TestTopicsFromPayload(t *testing.T) {
+	tests := []struct {
+		payload interface{}
+		want    []string
+	}{
+		{map[string]interface{}{"topic": "status"}, []string{"status"}},
+		{map[string]interface{}{"topics": []interface{}{"scale/*", "status"}}, []string{"scale/*", "status"}},
+		{map[string]interface{}{"topics": []interface{}{""}}, nil},
+		{map[string]interface{}{"topics": []interface{}{1}}, nil},
+		{map[string]interface{}{}, nil},
+		{"status", nil},
+	}
+	for _, tt := range tests {
+		got, err := topicsFromPayload(tt.payload)
+		if tt.want == nil {
+			if err == nil {
+				t.Errorf("topicsFromPayload(%v) = %v, want an error", tt.payload, got)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("topicsFromPayload(%v) = %v, %v, want %v", tt.payload, got, err, tt.want)
+		}
+	}
+}
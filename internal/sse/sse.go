@@ -0,0 +1,201 @@
+package sse
+
+import (
+	"bridge-serial/pkg/logger"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// keepAliveInterval is how often an idle stream receives a comment so
+// proxies do not close it
+const keepAliveInterval = 15 * time.Second
+
+// subscriberBuffer is how many events a slow subscriber may lag behind
+// before it is disconnected
+const subscriberBuffer = 64
+
+// Event is one server-sent event
+type Event struct {
+	ID   uint64
+	Type string
+	Data []byte
+}
+
+// Broker fans events out to Server-Sent Events streams and keeps the most
+// recent events so reconnecting clients can resume from Last-Event-ID
+type Broker struct {
+	mu          sync.Mutex
+	lastID      uint64
+	history     []Event
+	historySize int
+	subscribers map[chan Event]struct{}
+}
+
+// NewBroker creates a broker keeping the last historySize events
+func NewBroker(historySize int) *Broker {
+	return &Broker{
+		historySize: historySize,
+		subscribers: make(map[chan Event]struct{}),
+	}
+}
+
+// Publish assigns the next event ID and sends the event to every stream.
+// Streams that cannot keep up are closed; clients resume with Last-Event-ID.
+func (b *Broker) Publish(eventType string, data []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastID++
+	event := Event{ID: b.lastID, Type: eventType, Data: data}
+
+	if b.historySize > 0 {
+		if len(b.history) >= b.historySize {
+			b.history = b.history[1:]
+		}
+		b.history = append(b.history, event)
+	}
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+			logger.Warn("SSE client too slow, closing stream")
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Close ends every open stream, e.g. before the HTTP server shuts down.
+// The broker keeps its history and accepts new streams afterwards.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subscribers {
+		delete(b.subscribers, ch)
+		close(ch)
+	}
+}
+
+// Count returns the number of open streams
+func (b *Broker) Count() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subscribers)
+}
+
+// subscribe registers a stream and, for a resuming client, returns the
+// history after lastID. When lastID is ahead of the broker, which restarted
+// since, all history is returned.
+func (b *Broker) subscribe(lastID uint64, resume bool) (chan Event, []Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch := make(chan Event, subscriberBuffer)
+	b.subscribers[ch] = struct{}{}
+
+	if !resume {
+		return ch, nil
+	}
+	if lastID > b.lastID {
+		lastID = 0
+	}
+	var missed []Event
+	for _, event := range b.history {
+		if event.ID > lastID {
+			missed = append(missed, event)
+		}
+	}
+	return ch, missed
+}
+
+func (b *Broker) unsubscribe(ch chan Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subscribers[ch]; ok {
+		delete(b.subscribers, ch)
+		close(ch)
+	}
+}
+
+// ServeHTTP streams events as text/event-stream. A client resuming after a
+// disconnect first receives the buffered events newer than its Last-Event-ID
+// header, or lastEventId query parameter; new clients only receive live events.
+func (b *Broker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	ch, missed := b.subscribe(lastEventID(r))
+	defer b.unsubscribe(ch)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	// resumed clients get missed events; the retry hint applies to everyone
+	fmt.Fprintf(w, "retry: %d\n\n", time.Second.Milliseconds())
+	for _, event := range missed {
+		writeEvent(w, event)
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-ch:
+			if !ok {
+				return
+			}
+			writeEvent(w, event)
+			flusher.Flush()
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+// writeEvent writes an event in the text/event-stream format
+func writeEvent(w http.ResponseWriter, event Event) {
+	fmt.Fprintf(w, "id: %d\n", event.ID)
+	if event.Type != "" {
+		fmt.Fprintf(w, "event: %s\n", event.Type)
+	}
+	for _, line := range strings.Split(string(event.Data), "\n") {
+		fmt.Fprintf(w, "data: %s\n", line)
+	}
+	fmt.Fprint(w, "\n")
+}
+
+// lastEventID returns the ID the client last received and whether it sent
+// one, i.e. whether it is resuming a stream
+func lastEventID(r *http.Request) (uint64, bool) {
+	value := r.Header.Get("Last-Event-ID")
+	if value == "" {
+		value = r.URL.Query().Get("lastEventId")
+	}
+	id, err := strconv.ParseUint(strings.TrimSpace(value), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,60 @@
+package sse
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// firstEventID connects to the stream and returns the id of the first event
+// received, publishing a live event once the stream is open
+func firstEventID(t *testing.T, b *Broker, url, lastEventID string) string {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "retry:") {
+			b.Publish("scale_data", []byte(`{"value":3}`))
+		}
+		if id, ok := strings.CutPrefix(line, "id: "); ok {
+			return id
+		}
+	}
+	t.Fatalf("stream ended without an event: %v", scanner.Err())
+	return ""
+}
+
+func TestHistoryOnlyForResumingClients(t *testing.T) {
+	b := NewBroker(16)
+	server := httptest.NewServer(b)
+	defer server.Close()
+
+	b.Publish("scale_data", []byte(`{"value":1}`))
+	b.Publish("scale_data", []byte(`{"value":2}`))
+
+	// a new client starts with the live event, not the history
+	if id := firstEventID(t, b, server.URL, ""); id != "3" {
+		t.Errorf("new client first event = %s, want 3", id)
+	}
+	// a resuming client first gets what it missed
+	if id := firstEventID(t, b, server.URL, "1"); id != "2" {
+		t.Errorf("resumed client first event = %s, want 2", id)
+	}
+}
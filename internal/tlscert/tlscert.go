@@ -0,0 +1,231 @@
+package tlscert
+
+import (
+	"bridge-serial/pkg/logger"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	caValidity   = 10 * 365 * 24 * time.Hour
+	leafValidity = 825 * 24 * time.Hour // the longest validity macOS and iOS accept for server certificates
+	renewBefore  = 30 * 24 * time.Hour
+)
+
+// Files holds the PEM file paths of a local certificate authority and the
+// localhost certificate it signed
+type Files struct {
+	CACert string
+	CAKey  string
+	Cert   string
+	Key    string
+}
+
+// FilesIn returns the certificate file paths inside dir
+func FilesIn(dir string) *Files {
+	return &Files{
+		CACert: filepath.Join(dir, "ca.pem"),
+		CAKey:  filepath.Join(dir, "ca-key.pem"),
+		Cert:   filepath.Join(dir, "localhost.pem"),
+		Key:    filepath.Join(dir, "localhost-key.pem"),
+	}
+}
+
+// EnsureLocalhost loads the CA and localhost certificate from dir, creating
+// the CA on first run and reissuing the localhost certificate when it is
+// missing or about to expire. The CA is kept so operators only trust it once,
+// and a CA with only one of its two files left is an error rather than
+// replaced.
+func EnsureLocalhost(dir string) (*Files, error) {
+	files := FilesIn(dir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create certificate directory: %w", err)
+	}
+
+	caCert, caKey, err := loadPair(files.CACert, files.CAKey)
+	if errors.Is(err, os.ErrNotExist) {
+		caCert, caKey, err = createMissingCA(files)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load local CA: %w", err)
+	}
+
+	leaf, _, err := loadPair(files.Cert, files.Key)
+	if err == nil && time.Until(leaf.NotAfter) > renewBefore && leaf.CheckSignatureFrom(caCert) == nil {
+		return files, nil
+	}
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		logger.Warn("reissuing localhost certificate: %v", err)
+	}
+
+	if err := createLeaf(files, caCert, caKey); err != nil {
+		return nil, fmt.Errorf("failed to issue localhost certificate: %w", err)
+	}
+	return files, nil
+}
+
+// createMissingCA creates the CA when neither of its files exists. Replacing
+// a lone certificate or key would silently invalidate the CA that browsers
+// and operating systems were told to trust.
+func createMissingCA(files *Files) (*x509.Certificate, crypto.Signer, error) {
+	certExists := fileExists(files.CACert)
+	keyExists := fileExists(files.CAKey)
+	switch {
+	case certExists && !keyExists:
+		return nil, nil, fmt.Errorf("%s exists but its key %s is missing; restore the key, or delete the certificate to create a new CA that must be trusted again", files.CACert, files.CAKey)
+	case keyExists && !certExists:
+		return nil, nil, fmt.Errorf("%s exists but its certificate %s is missing; restore the certificate, or delete the key to create a new CA that must be trusted again", files.CAKey, files.CACert)
+	}
+	return createCA(files)
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// createCA generates a self-signed CA and writes it to files. Name
+// constraints limit it to loopback names, so a leaked key cannot be used to
+// impersonate other sites to machines that trust it.
+func createCA(files *Files) (*x509.Certificate, crypto.Signer, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "Bridge Serial Local CA", Organization: []string{"bridge-serial"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(caValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+		// RFC 5280 requires name constraints to be critical
+		PermittedDNSDomainsCritical: true,
+		PermittedDNSDomains:         []string{"localhost"},
+		PermittedIPRanges: []*net.IPNet{
+			{IP: net.IPv4(127, 0, 0, 0).To4(), Mask: net.CIDRMask(8, 32)},
+			{IP: net.IPv6loopback, Mask: net.CIDRMask(128, 128)},
+		},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := writePair(files.CACert, files.CAKey, der, key); err != nil {
+		return nil, nil, err
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, err
+	}
+	logger.Info("generated local CA certificate %s", files.CACert)
+	return cert, key, nil
+}
+
+// createLeaf issues a localhost server certificate signed by the CA
+func createLeaf(files *Files, caCert *x509.Certificate, caKey crypto.Signer) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+
+	serial, err := randomSerial()
+	if err != nil {
+		return err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: "localhost", Organization: []string{"bridge-serial"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(leafValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, caCert, key.Public(), caKey)
+	if err != nil {
+		return err
+	}
+	if err := writePair(files.Cert, files.Key, der, key); err != nil {
+		return err
+	}
+	logger.Info("issued localhost certificate %s", files.Cert)
+	return nil
+}
+
+// loadPair reads a PEM certificate and its PKCS#8 private key
+func loadPair(certPath, keyPath string) (*x509.Certificate, crypto.Signer, error) {
+	certPEM, err := os.ReadFile(certPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyPEM, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	certBlock, _ := pem.Decode(certPEM)
+	if certBlock == nil {
+		return nil, nil, fmt.Errorf("no certificate found in %s", certPath)
+	}
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse %s: %w", certPath, err)
+	}
+
+	keyBlock, _ := pem.Decode(keyPEM)
+	if keyBlock == nil {
+		return nil, nil, fmt.Errorf("no private key found in %s", keyPath)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse %s: %w", keyPath, err)
+	}
+	key, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, nil, fmt.Errorf("unsupported private key type in %s", keyPath)
+	}
+	return cert, key, nil
+}
+
+// writePair writes a DER certificate and its key as PEM, the key readable by the owner only
+func writePair(certPath, keyPath string, der []byte, key crypto.Signer) error {
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		return err
+	}
+	return os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644)
+}
+
+func randomSerial() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
@@ -0,0 +1,242 @@
+package tlscert
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// loadCert reads a PEM certificate written by EnsureLocalhost
+func loadCert(t *testing.T, certPath, keyPath string) *x509.Certificate {
+	t.Helper()
+	cert, _, err := loadPair(certPath, keyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func TestCAIsLimitedToLoopback(t *testing.T) {
+	files, err := EnsureLocalhost(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	caCert, caKey, err := loadPair(files.CACert, files.CAKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(caCert)
+
+	leaf := loadCert(t, files.Cert, files.Key)
+	for _, name := range []string{"localhost", "127.0.0.1", "::1"} {
+		if _, err := leaf.Verify(x509.VerifyOptions{DNSName: name, Roots: roots}); err != nil {
+			t.Errorf("localhost certificate does not verify for %s: %v", name, err)
+		}
+	}
+
+	// a certificate for another site signed with the CA key is rejected
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: caCert.SerialNumber,
+		Subject:      pkix.Name{CommonName: "bank.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:     []string{"bank.example.com"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, caCert, key.Public(), caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = forged.Verify(x509.VerifyOptions{DNSName: "bank.example.com", Roots: roots})
+	if _, ok := err.(x509.CertificateInvalidError); !ok {
+		t.Fatalf("certificate outside the name constraints verified: %v", err)
+	}
+}
+
+func TestMissingCAKeyIsAnError(t *testing.T) {
+	dir := t.TempDir()
+	files, err := EnsureLocalhost(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caPEM, err := os.ReadFile(files.CACert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(files.CAKey); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = EnsureLocalhost(dir)
+	if err == nil || !strings.Contains(err.Error(), "ca-key.pem is missing") {
+		t.Fatalf("EnsureLocalhost without the CA key = %v", err)
+	}
+	if after, _ := os.ReadFile(files.CACert); !bytes.Equal(after, caPEM) {
+		t.Fatal("trusted CA certificate was overwritten")
+	}
+}
+
+func TestEnsureLocalhost(t *testing.T) {
+	dir := t.TempDir()
+	files, err := EnsureLocalhost(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{files.CAKey, files.Key} {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if perm := info.Mode().Perm(); perm != 0600 {
+			t.Errorf("%s mode = %v, want 0600", path, perm)
+		}
+	}
+
+	caCert := loadCert(t, files.CACert, files.CAKey)
+	if !caCert.IsCA || !caCert.MaxPathLenZero || caCert.KeyUsage&x509.KeyUsageCertSign == 0 {
+		t.Errorf("CA certificate is not a path length zero signing CA: %+v", caCert)
+	}
+	leaf := loadCert(t, files.Cert, files.Key)
+	if err := leaf.CheckSignatureFrom(caCert); err != nil {
+		t.Fatalf("localhost certificate is not signed by the CA: %v", err)
+	}
+	if leaf.IsCA || len(leaf.DNSNames) != 1 || leaf.DNSNames[0] != "localhost" || len(leaf.IPAddresses) != 2 {
+		t.Errorf("localhost certificate names = %v %v", leaf.DNSNames, leaf.IPAddresses)
+	}
+	if validity := leaf.NotAfter.Sub(leaf.NotBefore); validity > leafValidity+time.Hour {
+		t.Errorf("localhost certificate is valid for %s, longer than clients accept", validity)
+	}
+}
+
+func TestEnsureLocalhostReload(t *testing.T) {
+	read := func(t *testing.T, path string) []byte {
+		t.Helper()
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+
+	tests := []struct {
+		name     string
+		prepare  func(t *testing.T, files *Files)
+		reissued bool
+	}{
+		{
+			name:    "valid certificate is kept",
+			prepare: func(t *testing.T, files *Files) {},
+		},
+		{
+			name: "missing certificate",
+			prepare: func(t *testing.T, files *Files) {
+				os.Remove(files.Cert)
+			},
+			reissued: true,
+		},
+		{
+			name: "unreadable certificate",
+			prepare: func(t *testing.T, files *Files) {
+				os.WriteFile(files.Cert, []byte("not a certificate"), 0644)
+			},
+			reissued: true,
+		},
+		{
+			name: "certificate about to expire",
+			prepare: func(t *testing.T, files *Files) {
+				caCert, caKey, err := loadPair(files.CACert, files.CAKey)
+				if err != nil {
+					t.Fatal(err)
+				}
+				issueTestLeaf(t, files, caCert, caKey, time.Now().Add(renewBefore-time.Hour))
+			},
+			reissued: true,
+		},
+		{
+			name: "certificate from another CA",
+			prepare: func(t *testing.T, files *Files) {
+				other, err := EnsureLocalhost(t.TempDir())
+				if err != nil {
+					t.Fatal(err)
+				}
+				os.WriteFile(files.Cert, read(t, other.Cert), 0644)
+				os.WriteFile(files.Key, read(t, other.Key), 0600)
+			},
+			reissued: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			files, err := EnsureLocalhost(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			caPEM := read(t, files.CACert)
+			tt.prepare(t, files)
+			before, _ := os.ReadFile(files.Cert)
+
+			if _, err := EnsureLocalhost(dir); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(read(t, files.CACert), caPEM) {
+				t.Fatal("CA certificate changed on reload")
+			}
+			after := read(t, files.Cert)
+			if reissued := !bytes.Equal(before, after); reissued != tt.reissued {
+				t.Fatalf("reissued = %v, want %v", reissued, tt.reissued)
+			}
+
+			caCert := loadCert(t, files.CACert, files.CAKey)
+			leaf := loadCert(t, files.Cert, files.Key)
+			if err := leaf.CheckSignatureFrom(caCert); err != nil || time.Until(leaf.NotAfter) < renewBefore {
+				t.Fatalf("certificate after reload is not usable: expires %s, %v", leaf.NotAfter, err)
+			}
+		})
+	}
+}
+
+// issueTestLeaf writes a localhost certificate expiring at notAfter
+func issueTestLeaf(t *testing.T, files *Files, caCert *x509.Certificate, caKey crypto.Signer, notAfter time.Time) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+		DNSNames:     []string{"localhost"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, caCert, key.Public(), caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writePair(files.Cert, files.Key, der, key); err != nil {
+		t.Fatal(err)
+	}
+}
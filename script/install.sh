@@ -52,6 +52,7 @@ else
   echo "Creating config.json with empty user/password..."
   cat > "$CONFIG_FILE" <<'EOF'
 {
+  "version": 2,
   "user": "",
   "password": ""
 }